@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ramitmittal/hitman/internal/httpclient"
+)
+
+// Results of the request under the cursor in two environments
+type compareMsg struct {
+	environments []string
+	results      []*httpclient.HitResult
+}
+
+// Start typing the names of the environments to send the request under the cursor to,
+// or switch the viewport back to the last result when it compares environments
+func (m *model) toggleCompareView() {
+	if m.compareView {
+		m.compareView = false
+		m.showResultOrNothing()
+		return
+	}
+
+	m.unsetError()
+	m.cookieView = false
+	m.tokenView = false
+	m.pickerView = false
+	m.diffView = false
+	m.compareNaming = true
+	m.compareInput = textinput.New()
+	m.compareInput.Width = m.windowWidth - 20
+	m.compareInput.Prompt = "compare environments: "
+	m.compareInput.SetValue(m.compareEnvironments)
+	m.compareInput.CursorEnd()
+	m.compareInput.Focus()
+	m.textarea.Blur()
+	m.viewport.GotoTop()
+	m.updateCompareInputView()
+}
+
+func (m *model) stopCompareNaming() {
+	m.compareNaming = false
+	m.compareInput.Blur()
+	m.textarea.Focus()
+}
+
+// Handle a key while the names of the environments are typed
+func (m *model) updateCompareInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.stopCompareNaming()
+		m.showResultOrNothing()
+		return nil
+	case tea.KeyEnter:
+		environments := strings.Fields(m.compareInput.Value())
+		if len(environments) != 2 {
+			m.showError(errors.New("enter the names of two environments"))
+			return nil
+		}
+		m.unsetError()
+		m.stopCompareNaming()
+		m.compareEnvironments = strings.Join(environments, " ")
+		_ = m.saveText()
+		m.viewport.SetContent("sending to " + environments[0] + " and " + environments[1] + "...")
+		return compareWrapper(m.client, m.textarea.Value(), m.textarea.Line(), environments)
+	}
+
+	var cmd tea.Cmd
+	m.compareInput, cmd = m.compareInput.Update(msg)
+	m.updateCompareInputView()
+	return cmd
+}
+
+// Show the input for the names of the environments and the environments to pick from
+func (m *model) updateCompareInputView() {
+	var view strings.Builder
+	view.WriteString(m.compareInput.View() + "\n")
+	environments, err := m.client.Environments()
+	if err != nil {
+		view.WriteString(err.Error() + "\n")
+	} else if len(environments) == 0 {
+		view.WriteString("No environments are defined.\n")
+	} else {
+		view.WriteString("Environments: " + strings.Join(environments, ", ") + "\n")
+	}
+	m.viewport.SetContent(view.String())
+}
+
+// Show the results of a request in two environments side by side
+func (m *model) showCompare(msg compareMsg) {
+	m.cookieView = false
+	m.tokenView = false
+	m.pickerView = false
+	m.diffView = false
+	m.compareView = true
+	m.compareNames = msg.environments
+	m.compareResults = msg.results
+	m.viewport.GotoTop()
+	m.updateCompareView()
+}
+
+// Returns the names of headers whose values differ between two results
+func differingHeaders(a *httpclient.HitResult, b *httpclient.HitResult) map[string]bool {
+	values := func(hr *httpclient.HitResult) map[string]string {
+		headers := map[string]string{}
+		if len(hr.ResponseHeaders) == 0 {
+			return headers
+		}
+		for _, line := range hr.ResponseHeaders[1:] {
+			if name, value, found := strings.Cut(line, " : "); found {
+				headers[name] += value + "\n"
+			}
+		}
+		return headers
+	}
+
+	va, vb := values(a), values(b)
+	differing := map[string]bool{}
+	for name, value := range va {
+		if vb[name] != value {
+			differing[name] = true
+		}
+	}
+	for name, value := range vb {
+		if va[name] != value {
+			differing[name] = true
+		}
+	}
+	return differing
+}
+
+// Convert the results of the compared environments into two columns for viewport
+// Status lines and headers that differ between them are highlighted
+func (m *model) updateCompareView() {
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	requestStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	differingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("11"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+	left, right := m.compareResults[0], m.compareResults[1]
+	differing := differingHeaders(left, right)
+
+	width := (m.windowWidth - 3) / 2
+	columns := make([]string, 2)
+	for i, result := range m.compareResults {
+		other := m.compareResults[1-i]
+
+		lines := []string{headingStyle.Render(m.compareNames[i])}
+		if request := result.RequestLine(); request != "" {
+			if !m.reveal {
+				request = httpclient.RedactResult([]string{request}, m.redactHeaders)[0]
+			}
+			lines = append(lines, requestStyle.Render(request))
+		}
+		if result.Err != nil {
+			lines = append(lines, errorStyle.Render(result.Err.Error()))
+		} else {
+			headers := result.ResponseHeaders
+			if !m.reveal {
+				headers = httpclient.RedactResult(headers, m.redactHeaders)
+			}
+			for j, line := range headers {
+				name, _, _ := strings.Cut(result.ResponseHeaders[j], " : ")
+				if j == 0 && (other.Err != nil || len(other.ResponseHeaders) == 0 || other.ResponseHeaders[0] != line) || j > 0 && differing[name] {
+					lines = append(lines, differingStyle.Render(line))
+				} else {
+					lines = append(lines, headerStyle.Render(line))
+				}
+			}
+			lines = append(lines, "", result.ResponseBody)
+		}
+
+		style := lipgloss.NewStyle().Width(width)
+		if i == 1 {
+			style = style.Border(lipgloss.NormalBorder(), false, false, false, true).PaddingLeft(1)
+		}
+		columns[i] = style.Render(strings.Join(lines, "\n"))
+	}
+	m.viewport.SetContent(lipgloss.JoinHorizontal(lipgloss.Top, columns...))
+}
+
+// Send the request under the cursor in two environments
+func compareWrapper(client *httpclient.Client, text string, line int, environments []string) tea.Cmd {
+	return func() tea.Msg {
+		return compareMsg{
+			environments: environments,
+			results:      client.SendInEnvironments(text, line, environments...),
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ramitmittal/hitman/internal/httpclient"
+)
+
+// Pin the last result as the baseline that later results of the same request are compared with,
+// or unpin it when it is pinned already
+func (m *model) togglePin() {
+	result := m.lastResult
+	if result == nil || result.Err != nil || len(result.ResponseHeaders) == 0 {
+		m.setError(errors.New("no result to pin"))
+		return
+	}
+
+	key := result.BaselineKey()
+	if m.baselines[key] == result {
+		delete(m.baselines, key)
+		m.showInfo("unpinned the baseline of " + key)
+		return
+	}
+	if m.baselines == nil {
+		m.baselines = map[string]*httpclient.HitResult{}
+	}
+	m.baselines[key] = result
+	if m.diffView {
+		m.diffView = false
+		m.showResultOrNothing()
+	}
+	m.showInfo("pinned the baseline of " + key + "; later results are compared with it")
+}
+
+// Returns the baseline pinned for the last result, or nothing when it has none or is the baseline itself
+func (m *model) baseline() *httpclient.HitResult {
+	if m.lastResult == nil || m.lastResult.Err != nil {
+		return nil
+	}
+	baseline := m.baselines[m.lastResult.BaselineKey()]
+	if baseline == m.lastResult {
+		return nil
+	}
+	return baseline
+}
+
+// Switch the viewport between the last result and its diff against the baseline
+func (m *model) toggleDiffView() {
+	if m.diffView {
+		m.diffView = false
+		m.showResultOrNothing()
+		return
+	}
+
+	if m.baseline() == nil {
+		m.showError(errors.New("no baseline to compare the last result with; pin one with Alt+P"))
+		return
+	}
+	m.unsetError()
+	m.cookieView = false
+	m.tokenView = false
+	m.pickerView = false
+	m.compareView = false
+	m.diffView = true
+	m.viewport.GotoTop()
+	m.updateDiffView()
+}
+
+// Convert the diff of the last result against its baseline into formatted text for viewport
+func (m *model) updateDiffView() {
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	sameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+
+	var formattedDiff strings.Builder
+	formattedDiff.WriteString(sameStyle.Render("compared with the baseline of "+m.lastResult.BaselineKey()) + "\n")
+	for _, line := range httpclient.DiffResults(m.baseline(), m.lastResult) {
+		text := line.Text
+		if !m.reveal {
+			text = httpclient.RedactResult([]string{text}, m.redactHeaders)[0]
+		}
+		switch line.Kind {
+		case httpclient.DiffHeading:
+			formattedDiff.WriteString("\n" + headingStyle.Render(text))
+		case httpclient.DiffRemoved:
+			formattedDiff.WriteString(removedStyle.Render("- " + text))
+		case httpclient.DiffAdded:
+			formattedDiff.WriteString(addedStyle.Render("+ " + text))
+		default:
+			formattedDiff.WriteString(sameStyle.Render("  " + text))
+		}
+		formattedDiff.WriteRune('\n')
+	}
+	m.viewport.SetContent(formattedDiff.String())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ramitmittal/hitman/internal/openapi"
+	"github.com/ramitmittal/hitman/internal/postman"
+	"github.com/ramitmittal/hitman/internal/store"
+)
+
+const importUsage = `usage:
+  hitman import openapi SPEC [FILE]      write a request for each operation of an OpenAPI 3 document
+  hitman import postman FILE... DIR      convert Postman v2.1 collections and environments
+
+OpenAPI requests are written to FILE, which must not exist yet, or printed when it is not given.
+Postman collections are written to a request file per folder in DIR, and environments to
+DIR/http-client.env.json, with secret values in DIR/http-client.private.env.json.`
+
+// Convert requests from other formats into request files
+func runImport(args []string) error {
+	if len(args) < 2 {
+		return errors.New(importUsage)
+	}
+
+	switch {
+	case args[0] == "openapi" && len(args) <= 3:
+		doc, err := openapi.Load(args[1])
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			fmt.Print(doc.RequestFile())
+			return nil
+		}
+		return createFile(args[2], doc.RequestFile())
+
+	case args[0] == "postman" && len(args) >= 3:
+		return importPostman(args[1:len(args)-1], args[len(args)-1])
+	}
+	return errors.New(importUsage)
+}
+
+// Convert Postman collections and environments into request files and environments in dir
+// Nothing is written when one of the request files already exists
+func importPostman(files []string, dir string) error {
+	var collections []*postman.Collection
+	var environments []*postman.Environment
+	for _, file := range files {
+		c, env, err := postman.Load(file)
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			collections = append(collections, c)
+		} else {
+			environments = append(environments, env)
+		}
+	}
+
+	requestFiles, variables, warnings := postman.Convert(collections, environments)
+	for _, f := range requestFiles {
+		if _, err := os.Lstat(filepath.Join(dir, f.Name)); err == nil {
+			return errors.New(filepath.Join(dir, f.Name) + " already exists")
+		}
+	}
+
+	for _, f := range requestFiles {
+		if err := createFile(filepath.Join(dir, f.Name), f.Text); err != nil {
+			return err
+		}
+		fmt.Println(filepath.Join(dir, f.Name))
+	}
+	for _, vars := range variables {
+		if err := store.SaveProjectEnvironment(dir, vars.Name, vars.Values, vars.Secrets); err != nil {
+			return err
+		}
+		fmt.Println("environment " + vars.Name)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "warning: "+warning)
+	}
+	return nil
+}
+
+// Write a file that must not exist yet
+func createFile(file string, text string) error {
+	if _, err := os.Lstat(file); err == nil {
+		return errors.New(file + " already exists")
+	}
+	return store.SaveFile(file, text)
+}
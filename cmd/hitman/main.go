@@ -2,15 +2,20 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/ramitmittal/hitman/internal/httpclient"
+	"github.com/ramitmittal/hitman/internal/openapi"
 	"github.com/ramitmittal/hitman/internal/store"
 )
 
@@ -49,10 +54,132 @@ type model struct {
 
 	// text area for user input; rendered below result viewport
 	textarea textarea.Model
+
+	// sends requests typed into the textarea
+	client *httpclient.Client
+
+	// true while the viewport lists stored cookies instead of the last result
+	cookieView bool
+
+	// cookies from all sessions listed in the cookie view
+	cookies []sessionCookie
+
+	// the index of cookies that is selected in the cookie view
+	cookieSelectedIndex int
+
+	// result of the last request, kept to switch back from the token exchange view
+	lastResult *httpclient.HitResult
+
+	// true while the viewport shows the OAuth2 token exchange of the last request
+	tokenView bool
+
+	// result shown in the viewport
+	result *httpclient.HitResult
+
+	// headers hidden besides Authorization and Cookie
+	redactHeaders []string
+
+	// true when sensitive values are shown, copied and saved as they are
+	reveal bool
+
+	// number of backups of the input to keep
+	backups int
+
+	// error component shown for the last failed save; empty when the last save succeeded
+	saveErrComponent string
+
+	// true after quitting was stopped because the input could not be saved
+	quitUnsaved bool
+
+	// request file open in the textarea; empty for the saved input
+	file string
+
+	// workspace directory whose request files are listed in the file picker
+	dir string
+
+	// directory hitman was started in
+	workDir string
+
+	// true while the viewport lists request files instead of the last result
+	pickerView bool
+
+	// entries of the file picker
+	pickerFiles []string
+
+	// the index of pickerFiles that is selected in the file picker
+	pickerSelectedIndex int
+
+	// whether a name is being typed for a new file or a rename
+	nameAction int
+
+	// input for the name of a new or renamed file
+	nameInput textinput.Model
+
+	// true while the viewport lists operations of an OpenAPI document to insert requests for
+	specView bool
+
+	// path of the last OpenAPI document that operations were inserted from
+	specPath string
+
+	// input for the path of the document, then for the text that operations are filtered with
+	specInput textinput.Model
+
+	// operations of the loaded document; nil while its path is typed
+	specOps []openapi.Operation
+
+	// indices of specOps that match the typed text, best match first
+	specMatches []int
+
+	// the index of specMatches that is selected in the operation picker
+	specSelectedIndex int
+
+	// server URL of the loaded document
+	specBaseURL string
+
+	// results that later results of the same request are compared with, keyed by request line
+	baselines map[string]*httpclient.HitResult
+
+	// true while the viewport shows how the last result differs from its baseline
+	diffView bool
+
+	// true while the names of the environments to compare are typed
+	compareNaming bool
+
+	// input for the names of the environments to compare
+	compareInput textinput.Model
+
+	// names of the environments that were compared last, separated by a space
+	compareEnvironments string
+
+	// true while the viewport shows the results of a request in two environments side by side
+	compareView bool
+
+	// environments shown in the compare view and their results
+	compareNames   []string
+	compareResults []*httpclient.HitResult
+}
+
+// Sent when the input is due to be saved
+type autosaveMsg struct{}
+
+// how often the input is saved while it is being edited
+const autosaveInterval = 10 * time.Second
+
+// Schedule the next autosave
+func autosave() tea.Cmd {
+	return tea.Tick(autosaveInterval, func(time.Time) tea.Msg {
+		return autosaveMsg{}
+	})
+}
+
+// A cookie and the name of the session that stores it
+type sessionCookie struct {
+	session string
+	cookie  store.Cookie
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return autosave()
 }
 
 func (m model) View() string {
@@ -78,25 +205,63 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.windowWidth = msg.Width
 		m.windowHeight = msg.Height
 		m.resetTitle()
+		m.initHelp()
 		m.initViewport()
 		m.initTextarea()
-		m.initHelp()
 		m.ready = true
 
 	case tea.KeyMsg:
+		if m.nameAction != nameNone && msg.Type != tea.KeyCtrlC {
+			return m, m.updateNaming(msg)
+		}
+		if m.specView && msg.Type != tea.KeyCtrlC {
+			return m, m.updateSpecInput(msg)
+		}
+		if m.compareNaming && msg.Type != tea.KeyCtrlC {
+			return m, m.updateCompareInput(msg)
+		}
+
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyEsc:
-			store.SaveText(m.textarea.Value())
+			if err := m.saveText(); err != nil && !m.quitUnsaved {
+				// quit without saving only when asked twice
+				m.quitUnsaved = true
+				m.showError(fmt.Errorf("could not save input: %w; press %s again to quit without saving", err, msg.String()))
+				return m, nil
+			}
 			return m, tea.Quit
 
 		case tea.KeyTab:
-			return m, hitWrapper(m.textarea.Value())
+			_ = m.saveText()
+			return m, hitWrapper(m.client, m.textarea.Value(), m.textarea.Line())
+
+		case tea.KeyEnter:
+			if m.pickerView {
+				m.openSelectedFile()
+				stopPropogation = true
+			}
 
 		case tea.KeyCtrlDown:
-			m.scrollDown()
+			if m.pickerView {
+				m.selectPickerFile(m.pickerSelectedIndex + 1)
+			} else if m.cookieView {
+				m.selectCookie(m.cookieSelectedIndex + 1)
+			} else if m.diffView || m.compareView {
+				m.viewport.LineDown(1)
+			} else {
+				m.scrollDown()
+			}
 			stopPropogation = true
 		case tea.KeyCtrlUp:
-			m.scrollUp()
+			if m.pickerView {
+				m.selectPickerFile(m.pickerSelectedIndex - 1)
+			} else if m.cookieView {
+				m.selectCookie(m.cookieSelectedIndex - 1)
+			} else if m.diffView || m.compareView {
+				m.viewport.LineUp(1)
+			} else {
+				m.scrollUp()
+			}
 			stopPropogation = true
 		case tea.KeyRunes:
 			if msg.Alt {
@@ -107,12 +272,65 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.copyHeaders()
 				case "d":
 					m.copyHighlight()
+				case "k":
+					m.toggleCookieView()
+				case "o":
+					m.toggleTokenView()
+				case "r":
+					m.toggleReveal()
+				case "x":
+					if m.cookieView {
+						m.deleteCookie()
+					}
+				case "f":
+					m.togglePicker()
+				case "n":
+					if m.pickerView {
+						m.startNaming(nameCreate)
+					}
+				case "e":
+					if m.pickerView {
+						m.startNaming(nameRename)
+					}
+				case "i":
+					m.toggleSpecView()
+				case "p":
+					m.togglePin()
+				case "v":
+					m.toggleDiffView()
+				case "c":
+					m.toggleCompareView()
 				}
 				stopPropogation = true
 			}
 		}
 
+	case autosaveMsg:
+		if m.ready {
+			_ = m.saveText()
+		}
+		return m, autosave()
+
+	case compareMsg:
+		m.showCompare(msg)
+
 	case *httpclient.HitResult:
+		m.cookieView = false
+		m.tokenView = false
+		if m.specView {
+			m.stopSpecView()
+		}
+		if m.compareNaming {
+			m.stopCompareNaming()
+		}
+		if m.pickerView {
+			m.pickerView = false
+			m.nameAction = nameNone
+			m.textarea.Focus()
+		}
+		m.lastResult = msg
+		m.diffView = false
+		m.compareView = false
 		if msg.Err != nil {
 			m.setError(msg.Err)
 			m.viewport.SetContent("")
@@ -121,6 +339,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.unsetError()
 			}
 			m.setResult(msg)
+			if m.baseline() != nil {
+				m.diffView = true
+				m.viewport.GotoTop()
+				m.updateDiffView()
+			}
 		}
 	}
 
@@ -155,14 +378,25 @@ func (m *model) errorTitle() {
 		Render(m.titlePlainText)
 }
 
-// Set value for error component
+// Set value for error component and clear the viewport
 func (m *model) setError(err error) {
-	m.errComponent = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(err.Error())
-	m.errorTitle()
+	m.showError(err)
 	m.viewport.SetContent("")
 	m.viewportSelectedLineIndex = 0
 }
 
+// Set value for error component and keep the viewport as it is
+func (m *model) showError(err error) {
+	m.errComponent = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(err.Error())
+	m.errorTitle()
+}
+
+// Set value for error component to a message that is not an error
+func (m *model) showInfo(text string) {
+	m.errComponent = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render(text)
+	m.resetTitle()
+}
+
 // Unset value for error component
 func (m *model) unsetError() {
 	m.errComponent = ""
@@ -171,7 +405,7 @@ func (m *model) unsetError() {
 
 // Initialize the viewport component
 func (m *model) initViewport() {
-	m.viewport = viewport.New(m.windowWidth, calculateHeightForViewport(m.windowWidth, m.windowHeight))
+	m.viewport = viewport.New(m.windowWidth, calculateHeightForViewport(m.windowHeight, lipgloss.Height(m.helpComponent)))
 	m.viewport.KeyMap = viewport.KeyMap{}
 	m.viewport.SetContent("")
 }
@@ -181,12 +415,13 @@ func (m *model) initTextarea() {
 	m.textarea = textarea.New()
 	m.textarea.SetWidth(m.windowWidth)
 	m.textarea.SetHeight(6)
+	m.textarea.CharLimit = 0
 	m.textarea.Prompt = "┃ "
 	m.textarea.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	m.textarea.ShowLineNumbers = false
 
 	if !m.ready {
-		m.textarea.SetValue(store.LoadText())
+		m.textarea.SetValue(m.loadText())
 	}
 	for i := 0; i < m.textarea.LineCount(); i++ {
 		m.textarea.CursorUp()
@@ -205,7 +440,7 @@ func (m *model) initHelp() {
 			"Ctrl+C", "quit",
 		},
 		{
-			"Tab", "send request",
+			"Tab", "send request under cursor",
 		},
 		{
 			"Ctrl+Up", "scroll result ↑",
@@ -222,6 +457,39 @@ func (m *model) initHelp() {
 		{
 			"Alt+D", "copy selected header",
 		},
+		{
+			"Alt+K", "cookies",
+		},
+		{
+			"Alt+X", "delete selected cookie",
+		},
+		{
+			"Alt+O", "OAuth2 token exchange",
+		},
+		{
+			"Alt+R", "reveal secrets",
+		},
+		{
+			"Alt+F", "files",
+		},
+		{
+			"Alt+N", "new file",
+		},
+		{
+			"Alt+E", "rename selected file",
+		},
+		{
+			"Alt+I", "insert request from OpenAPI document",
+		},
+		{
+			"Alt+P", "pin result as baseline",
+		},
+		{
+			"Alt+V", "diff with baseline",
+		},
+		{
+			"Alt+C", "compare environments",
+		},
 	}
 	var sb strings.Builder
 	for i, item := range bindings {
@@ -273,15 +541,22 @@ func (m *model) copyHighlight() {
 func (m *model) setResult(result *httpclient.HitResult) {
 	rawResult := make([]string, 0, len(result.RequestHeaders)+len(result.ResponseHeaders)+3)
 
-	rawResult = append(rawResult, result.RequestHeaders...)
+	requestHeaders, responseHeaders := result.RequestHeaders, result.ResponseHeaders
+	if !m.reveal {
+		requestHeaders = httpclient.RedactResult(requestHeaders, m.redactHeaders)
+		responseHeaders = httpclient.RedactResult(responseHeaders, m.redactHeaders)
+	}
+
+	rawResult = append(rawResult, requestHeaders...)
 	rawResult = append(rawResult, "\n")
-	rawResult = append(rawResult, result.ResponseHeaders...)
+	rawResult = append(rawResult, responseHeaders...)
 	rawResult = append(rawResult, "\n")
 	rawResult = append(rawResult, result.ResponseBody)
 
 	if m.viewportSelectedLineIndex > len(rawResult) {
 		m.viewportSelectedLineIndex = 0
 	}
+	m.result = result
 	m.rawResult = rawResult
 	m.updateFormattedResult()
 }
@@ -328,9 +603,203 @@ func (m *model) scrollUp() {
 	m.viewport.LineUp(1)
 }
 
-func hitWrapper(text string) tea.Cmd {
+// Open or close the list of stored cookies in the viewport
+func (m *model) toggleCookieView() {
+	if m.cookieView {
+		m.cookieView = false
+		if len(m.rawResult) == 0 {
+			m.viewport.SetContent("")
+		} else {
+			m.updateFormattedResult()
+		}
+		return
+	}
+
+	if err := m.loadCookies(); err != nil {
+		m.setError(err)
+		return
+	}
+	m.unsetError()
+	m.tokenView = false
+	m.pickerView = false
+	m.diffView = false
+	m.compareView = false
+	m.cookieView = true
+	m.cookieSelectedIndex = 0
+	m.viewport.GotoTop()
+	m.updateCookieView()
+}
+
+// Switch the viewport between the last result and the OAuth2 token request made for it
+func (m *model) toggleTokenView() {
+	if m.tokenView {
+		m.tokenView = false
+		m.showResult(m.lastResult)
+		return
+	}
+
+	if m.lastResult == nil || m.lastResult.TokenExchange == nil {
+		m.setError(errors.New("last request made no token exchange"))
+		return
+	}
+	m.cookieView = false
+	m.pickerView = false
+	m.diffView = false
+	m.compareView = false
+	m.tokenView = true
+	m.viewport.GotoTop()
+	m.showResult(m.lastResult.TokenExchange)
+}
+
+// Show a result in the viewport, or its error in the error component
+func (m *model) showResult(result *httpclient.HitResult) {
+	if result.Err != nil {
+		m.setError(result.Err)
+	} else {
+		m.unsetError()
+	}
+	if len(result.RequestHeaders) == 0 {
+		m.viewport.SetContent("")
+		return
+	}
+	m.setResult(result)
+}
+
+// Show or hide sensitive header and cookie values
+func (m *model) toggleReveal() {
+	m.reveal = !m.reveal
+	if m.cookieView {
+		m.updateCookieView()
+	} else if m.diffView {
+		m.updateDiffView()
+	} else if m.compareView {
+		m.updateCompareView()
+	} else if m.result != nil && len(m.rawResult) > 0 {
+		m.setResult(m.result)
+	}
+}
+
+// Save the textarea's contents, with credentials in the input moved to the secrets store
+// The textarea is left as it is and request files are saved as they are
+// Populates error component on failure and clears it once a later save succeeds
+func (m *model) saveText() error {
+	text := m.textarea.Value()
+
+	var err error
+	if m.file == "" {
+		if text, err = httpclient.StoreCredentials(text, m.redactHeaders); err == nil {
+			err = store.SaveText(text, m.backups)
+		}
+	} else {
+		err = store.SaveFile(m.file, text)
+	}
+	if err != nil {
+		m.showError(fmt.Errorf("could not save input: %w", err))
+		m.saveErrComponent = m.errComponent
+		return err
+	}
+	if m.saveErrComponent != "" {
+		if m.errComponent == m.saveErrComponent {
+			m.unsetError()
+		}
+		m.saveErrComponent = ""
+	}
+	m.quitUnsaved = false
+	return nil
+}
+
+// Read cookies of all saved sessions into the model
+func (m *model) loadCookies() error {
+	names, err := store.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	m.cookies = nil
+	for _, name := range names {
+		session, err := store.LoadSession(name)
+		if err != nil {
+			return err
+		}
+		for _, c := range session.List() {
+			m.cookies = append(m.cookies, sessionCookie{session: name, cookie: c})
+		}
+	}
+	return nil
+}
+
+// Attempts to delete the selected cookie from its session; populates error component on failure
+func (m *model) deleteCookie() {
+	if len(m.cookies) == 0 {
+		m.setError(errors.New("no cookie to delete"))
+		return
+	}
+
+	selected := m.cookies[m.cookieSelectedIndex]
+	session, err := store.LoadSession(selected.session)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	session.Delete(selected.cookie)
+	if err := store.SaveSession(session); err != nil {
+		m.setError(err)
+		return
+	}
+	if err := m.loadCookies(); err != nil {
+		m.setError(err)
+		return
+	}
+
+	m.unsetError()
+	m.selectCookie(m.cookieSelectedIndex)
+}
+
+func (m *model) selectCookie(index int) {
+	if index >= len(m.cookies) {
+		index = len(m.cookies) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	m.cookieSelectedIndex = index
+	m.updateCookieView()
+}
+
+// Convert the list of cookies into formatted text for viewport
+func (m *model) updateCookieView() {
+	highlightedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Background(lipgloss.Color("#FFFFFF"))
+	cookieStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+	if len(m.cookies) == 0 {
+		m.viewport.SetContent("No stored cookies.")
+		return
+	}
+
+	var formattedCookies strings.Builder
+	for idx, sc := range m.cookies {
+		value := sc.cookie.Value
+		if !m.reveal {
+			value = httpclient.Redacted
+		}
+		line := fmt.Sprintf("[%s] %s%s %s=%s", sc.session, sc.cookie.Domain, sc.cookie.Path, sc.cookie.Name, value)
+		if !sc.cookie.Expires.IsZero() {
+			line += " (expires " + sc.cookie.Expires.Format(time.RFC3339) + ")"
+		}
+		if idx == m.cookieSelectedIndex {
+			formattedCookies.WriteString(highlightedStyle.Render(line))
+		} else {
+			formattedCookies.WriteString(cookieStyle.Render(line))
+		}
+		formattedCookies.WriteRune('\n')
+	}
+	m.viewport.SetContent(formattedCookies.String())
+}
+
+// Send the request under the cursor
+func hitWrapper(client *httpclient.Client, text string, line int) tea.Cmd {
 	return func() tea.Msg {
-		return httpclient.Hit(text)
+		return client.Send(text, line)
 	}
 }
 
@@ -349,19 +818,71 @@ func generateTitlePlainText() string {
 	return "Hitman HTTP Client " + version
 }
 
-func calculateHeightForViewport(windowWidth, windowHeight int) int {
-	if windowWidth < 160 {
-		// because help text takes 2 lines
-		return windowHeight - 12
-	}
-	// because help text takes 1 line
-	return windowHeight - 11
+func calculateHeightForViewport(windowHeight, helpHeight int) int {
+	// title, error, textarea and the blank lines between them take 10 lines
+	return windowHeight - 10 - helpHeight
 }
 
 func main() {
+	configDir := flag.String("config", "", "directory of config files instead of "+store.ConfigDir())
+	collection := flag.String("collection", "", "open the named collection of request files")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: hitman [-config dir] [-collection name | path]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *configDir != "" {
+		store.SetConfigDir(*configDir)
+	}
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "secret" {
+		if err := runSecret(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "import" {
+		if err := runImport(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := store.MigrateInput(); err != nil {
+		log.Fatal(fmt.Errorf("could not move the saved input to %s: %w", store.TextPath(), err))
+	}
+	config, err := store.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	backups := 3
+	if config.Backups != nil {
+		backups = *config.Backups
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, file, root, err := openArgs(workDir, flag.Args(), *collection)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	m := model{
 		titlePlainText: generateTitlePlainText(),
+		redactHeaders:  config.RedactHeaders,
+		backups:        backups,
+		file:           file,
+		dir:            dir,
+		workDir:        workDir,
+		client: &httpclient.Client{
+			DefaultScheme: config.DefaultScheme,
+			BaseDir:       workDir,
+			Environment:   config.Environment,
+			ProjectDir:    root,
+		},
 	}
+	m.fileOpened()
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseAllMotion())
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/store"
+)
+
+const secretUsage = `usage:
+  hitman secret set NAME    read the value of a secret from stdin and store it
+  hitman secret rm NAME     delete a secret
+  hitman secret list        list the names of stored secrets
+
+Secrets are encrypted with the passphrase in ` + store.PassphraseEnv + ` when it is set,
+and with a key stored in the key file otherwise.`
+
+// Manage the secrets that requests use as {{$secret NAME}}
+func runSecret(args []string) error {
+	if len(args) == 0 {
+		return errors.New(secretUsage)
+	}
+
+	secrets, err := store.LoadSecrets()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+
+	case args[0] == "set" && len(args) == 2:
+		fmt.Fprintf(os.Stderr, "value for %s: ", args[1])
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && value == "" {
+			return errors.New("no value for " + args[1])
+		}
+		secrets[args[1]] = strings.TrimRight(value, "\r\n")
+		return store.SaveSecrets(secrets)
+
+	case args[0] == "rm" && len(args) == 2:
+		if _, prs := secrets[args[1]]; !prs {
+			return errors.New("unknown secret " + args[1])
+		}
+		delete(secrets, args[1])
+		return store.SaveSecrets(secrets)
+	}
+	return errors.New(secretUsage)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ramitmittal/hitman/internal/openapi"
+	"github.com/ramitmittal/hitman/internal/parser"
+)
+
+// Open or close the picker of operations from an OpenAPI document
+// The path of the document is asked first, starting with the last one used
+func (m *model) toggleSpecView() {
+	if m.specView {
+		m.stopSpecView()
+		m.showResultOrNothing()
+		return
+	}
+
+	m.unsetError()
+	m.cookieView = false
+	m.tokenView = false
+	m.pickerView = false
+	m.diffView = false
+	m.compareView = false
+	m.specView = true
+	m.specOps = nil
+	m.specInput = textinput.New()
+	m.specInput.Width = m.windowWidth - 20
+	m.specInput.Prompt = "OpenAPI document: "
+	m.specInput.SetValue(m.specPath)
+	m.specInput.CursorEnd()
+	m.specInput.Focus()
+	m.textarea.Blur()
+	m.viewport.GotoTop()
+	m.updateSpecView()
+}
+
+func (m *model) stopSpecView() {
+	m.specView = false
+	m.specInput.Blur()
+	m.textarea.Focus()
+}
+
+// Handle a key while the operation picker is open
+func (m *model) updateSpecInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.stopSpecView()
+		m.showResultOrNothing()
+		return nil
+	case tea.KeyEnter:
+		if m.specOps == nil {
+			m.loadSpec()
+		} else {
+			m.insertOperation()
+		}
+		return nil
+	case tea.KeyUp, tea.KeyCtrlUp:
+		m.selectOperation(m.specSelectedIndex - 1)
+		return nil
+	case tea.KeyDown, tea.KeyCtrlDown:
+		m.selectOperation(m.specSelectedIndex + 1)
+		return nil
+	}
+	if msg.Alt && string(msg.Runes) == "i" {
+		m.stopSpecView()
+		m.showResultOrNothing()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.specInput, cmd = m.specInput.Update(msg)
+	if m.specOps != nil {
+		m.filterOperations()
+	}
+	m.updateSpecView()
+	return cmd
+}
+
+// Read the operations of the document whose path was typed and start filtering them
+func (m *model) loadSpec() {
+	path := strings.TrimSpace(m.specInput.Value())
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(m.workDir, path)
+	}
+	doc, err := openapi.Load(path)
+	if err != nil {
+		m.showError(err)
+		return
+	}
+
+	m.unsetError()
+	m.specPath = m.specInput.Value()
+	m.specBaseURL = doc.BaseURL()
+	m.specOps = doc.Operations()
+	if m.specOps == nil {
+		m.specOps = []openapi.Operation{}
+	}
+	m.specInput.Prompt = "operation: "
+	m.specInput.SetValue("")
+	m.filterOperations()
+	m.updateSpecView()
+}
+
+// Match the operations against the typed text, best matches first
+func (m *model) filterOperations() {
+	query := m.specInput.Value()
+	type match struct {
+		index int
+		score int
+	}
+	var matches []match
+	for i, op := range m.specOps {
+		if score, ok := fuzzyScore(query, op.ID); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	m.specMatches = m.specMatches[:0]
+	for _, match := range matches {
+		m.specMatches = append(m.specMatches, match.index)
+	}
+	m.specSelectedIndex = 0
+}
+
+func (m *model) selectOperation(index int) {
+	if index >= len(m.specMatches) {
+		index = len(m.specMatches) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	m.specSelectedIndex = index
+	m.updateSpecView()
+}
+
+// Add the request of the selected operation to the end of the input
+// The server URL is defined with it unless the input already defines it
+func (m *model) insertOperation() {
+	if len(m.specMatches) == 0 {
+		return
+	}
+	op := m.specOps[m.specMatches[m.specSelectedIndex]]
+
+	text := strings.TrimRight(m.textarea.Value(), "\n")
+	if _, prs := parser.FileVariables(text)[openapi.BaseURLVariable]; !prs {
+		op.Request = "@" + openapi.BaseURLVariable + " = " + m.specBaseURL + "\n" + op.Request
+	}
+	if strings.TrimSpace(text) != "" {
+		text += "\n###\n"
+	}
+	m.textarea.SetValue(text + strings.TrimRight(op.Request, "\n"))
+
+	m.stopSpecView()
+	m.showResultOrNothing()
+}
+
+// Convert the operation picker into formatted text for viewport
+func (m *model) updateSpecView() {
+	highlightedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Background(lipgloss.Color("#FFFFFF"))
+	operationStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+	var formattedOps strings.Builder
+	formattedOps.WriteString(m.specInput.View() + "\n")
+	if m.specOps == nil {
+		formattedOps.WriteString("Enter the path of an OpenAPI 3 document in JSON or YAML.\n")
+	} else if len(m.specMatches) == 0 {
+		formattedOps.WriteString("No matching operations.\n")
+	}
+	for idx, i := range m.specMatches {
+		op := m.specOps[i]
+		line := op.ID + "  " + op.Method + " " + op.Path
+		if op.Summary != "" {
+			line += " • " + op.Summary
+		}
+		if idx == m.specSelectedIndex {
+			formattedOps.WriteString(highlightedStyle.Render(line))
+		} else {
+			formattedOps.WriteString(operationStyle.Render(line))
+		}
+		formattedOps.WriteRune('\n')
+	}
+	m.viewport.SetContent(formattedOps.String())
+}
+
+// Reports whether the characters of query appear in target in order, ignoring case
+// Matches at the start of words and runs of consecutive characters score higher
+func fuzzyScore(query string, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(target)
+
+	score, qi, last := 0, 0, -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if unicode.ToLower(t[ti]) != q[qi] {
+			continue
+		}
+		score++
+		if ti == last+1 {
+			score += 5
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) || unicode.IsUpper(t[ti]) && unicode.IsLower(t[ti-1]) {
+			score += 10
+		}
+		last = ti
+		qi++
+	}
+	return score, qi == len(q)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ramitmittal/hitman/internal/store"
+)
+
+// Entry of the file picker that switches back to the saved input
+const scratchEntry = "(scratch)"
+
+// What the name typed into the file picker is for
+const (
+	nameNone = iota
+	nameCreate
+	nameRename
+)
+
+// Returns the workspace directory and request file to open for the command line arguments,
+// and the root of the project that they belong to
+// A directory opens its first request file, and a file that does not exist yet is created on first save
+// Without arguments, the project around the working directory is opened if there is one
+func openArgs(workDir string, args []string, collection string) (dir string, file string, root string, err error) {
+	if collection != "" {
+		if len(args) > 0 {
+			return "", "", "", errors.New("-collection cannot be combined with a path")
+		}
+		if dir, err = store.CreateCollection(collection); err != nil {
+			return "", "", "", err
+		}
+		return dir, firstRequestFile(dir), "", nil
+	}
+
+	switch len(args) {
+	case 0:
+		if root, requests, ok := store.FindProject(workDir); ok {
+			file := firstRequestFile(requests)
+			if file == "" {
+				file = filepath.Join(requests, "requests"+store.RequestFileExt)
+			}
+			return requests, file, root, nil
+		}
+		dir, err = store.CreateCollection("default")
+		return dir, "", "", err
+	case 1:
+	default:
+		return "", "", "", errors.New("usage: hitman [-config dir] [-collection name | path]")
+	}
+
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return "", "", "", err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) && strings.HasSuffix(path, store.RequestFileExt) {
+		dir, file = filepath.Dir(path), path
+	} else if err != nil {
+		return "", "", "", err
+	} else if info.IsDir() {
+		dir, file = path, firstRequestFile(path)
+	} else {
+		dir, file = filepath.Dir(path), path
+	}
+
+	root, _, _ = store.FindProject(dir)
+	return dir, file, root, nil
+}
+
+// Returns the path of the first request file in a directory, or nothing when it has none
+func firstRequestFile(dir string) string {
+	if names, err := store.ListRequestFiles(dir); err == nil && len(names) > 0 {
+		return filepath.Join(dir, names[0])
+	}
+	return ""
+}
+
+// Returns the text to edit when hitman starts
+func (m *model) loadText() string {
+	if m.file == "" {
+		return store.LoadText()
+	}
+	text, err := store.LoadFile(m.file)
+	if err != nil {
+		m.showError(err)
+	}
+	return text
+}
+
+// Returns the name of the open file for the title bar
+func (m *model) fileTitle() string {
+	if m.file == "" {
+		return scratchEntry
+	}
+	if rel, err := filepath.Rel(m.workDir, m.file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return m.file
+}
+
+// Update the title bar and the directory that requests resolve relative paths against for the open file
+// Files of a project resolve them against the project root
+func (m *model) fileOpened() {
+	m.titlePlainText = generateTitlePlainText() + " • " + m.fileTitle()
+	m.resetTitle()
+	// requests that are being sent keep the client they were sent with
+	switch {
+	case m.file == "":
+		m.client = m.client.InDir(m.workDir)
+	case m.client.ProjectDir != "":
+		m.client = m.client.InDir(m.client.ProjectDir)
+	default:
+		m.client = m.client.InDir(filepath.Dir(m.file))
+	}
+}
+
+// Open or close the list of request files in the viewport
+func (m *model) togglePicker() {
+	if m.pickerView {
+		m.pickerView = false
+		m.nameAction = nameNone
+		m.showResultOrNothing()
+		return
+	}
+
+	if err := m.loadPickerFiles(); err != nil {
+		m.setError(err)
+		return
+	}
+	m.unsetError()
+	m.cookieView = false
+	m.tokenView = false
+	m.diffView = false
+	m.compareView = false
+	m.pickerView = true
+	m.pickerSelectedIndex = 0
+	for i, entry := range m.pickerFiles {
+		if filepath.Join(m.dir, entry) == m.file || (m.file == "" && entry == scratchEntry) {
+			m.pickerSelectedIndex = i
+		}
+	}
+	m.viewport.GotoTop()
+	m.updatePickerView()
+}
+
+// Show the last result in the viewport, or nothing when there is none
+func (m *model) showResultOrNothing() {
+	if len(m.rawResult) == 0 {
+		m.viewport.SetContent("")
+	} else {
+		m.updateFormattedResult()
+	}
+}
+
+// Read the request files of the workspace into the model
+func (m *model) loadPickerFiles() error {
+	names, err := store.ListRequestFiles(m.dir)
+	if err != nil {
+		return err
+	}
+	m.pickerFiles = append([]string{scratchEntry}, names...)
+	return nil
+}
+
+func (m *model) selectPickerFile(index int) {
+	if index >= len(m.pickerFiles) {
+		index = len(m.pickerFiles) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	m.pickerSelectedIndex = index
+	m.updatePickerView()
+}
+
+// Convert the list of request files into formatted text for viewport
+func (m *model) updatePickerView() {
+	highlightedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Background(lipgloss.Color("#FFFFFF"))
+	fileStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+	var formattedFiles strings.Builder
+	formattedFiles.WriteString(m.dir + "\n")
+	if m.nameAction != nameNone {
+		formattedFiles.WriteString(m.nameInput.View() + "\n")
+	}
+	for idx, name := range m.pickerFiles {
+		if idx == m.pickerSelectedIndex {
+			formattedFiles.WriteString(highlightedStyle.Render(name))
+		} else {
+			formattedFiles.WriteString(fileStyle.Render(name))
+		}
+		formattedFiles.WriteRune('\n')
+	}
+	m.viewport.SetContent(formattedFiles.String())
+}
+
+// Save the open file and open the one selected in the file picker
+func (m *model) openSelectedFile() {
+	var file string
+	if entry := m.pickerFiles[m.pickerSelectedIndex]; entry != scratchEntry {
+		file = filepath.Join(m.dir, entry)
+	}
+	m.openFile(file)
+}
+
+// Save the open file and open another one; an empty path opens the saved input
+func (m *model) openFile(file string) {
+	if err := m.saveText(); err != nil {
+		return
+	}
+
+	var text string
+	if file == "" {
+		text = store.LoadText()
+	} else {
+		var err error
+		if text, err = store.LoadFile(file); err != nil {
+			m.setError(err)
+			return
+		}
+	}
+
+	m.file = file
+	m.textarea.SetValue(text)
+	m.textarea.Focus()
+	m.pickerView = false
+	m.unsetError()
+	m.fileOpened()
+	m.showResultOrNothing()
+}
+
+// Start typing the name of a new file or the new name of the selected file
+func (m *model) startNaming(action int) {
+	entry := m.pickerFiles[m.pickerSelectedIndex]
+	if action == nameRename && entry == scratchEntry {
+		m.setError(errors.New("the saved input cannot be renamed"))
+		m.updatePickerView()
+		return
+	}
+
+	m.nameAction = action
+	m.nameInput = textinput.New()
+	m.nameInput.Width = m.windowWidth - 20
+	if action == nameCreate {
+		m.nameInput.Prompt = "new file: "
+	} else {
+		m.nameInput.Prompt = "rename to: "
+		m.nameInput.SetValue(strings.TrimSuffix(entry, store.RequestFileExt))
+		m.nameInput.CursorEnd()
+	}
+	m.nameInput.Focus()
+	m.textarea.Blur()
+	m.updatePickerView()
+}
+
+// Handle a key while a file name is typed
+func (m *model) updateNaming(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.stopNaming()
+		return nil
+	case tea.KeyEnter:
+		m.finishNaming()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	m.updatePickerView()
+	return cmd
+}
+
+func (m *model) stopNaming() {
+	m.nameAction = nameNone
+	m.textarea.Focus()
+	m.updatePickerView()
+}
+
+// Create or rename the file with the typed name
+func (m *model) finishNaming() {
+	name := m.nameInput.Value()
+	action := m.nameAction
+	m.stopNaming()
+
+	if action == nameCreate {
+		file, err := store.CreateRequestFile(m.dir, name)
+		if err != nil {
+			m.setError(err)
+			m.updatePickerView()
+			return
+		}
+		m.openFile(file)
+		return
+	}
+
+	selected := filepath.Join(m.dir, m.pickerFiles[m.pickerSelectedIndex])
+	renamed, err := store.RenameRequestFile(selected, name)
+	if err != nil {
+		m.setError(fmt.Errorf("could not rename %s: %w", filepath.Base(selected), err))
+		m.updatePickerView()
+		return
+	}
+	if selected == m.file {
+		m.file = renamed
+		m.fileOpened()
+	}
+	if err := m.loadPickerFiles(); err != nil {
+		m.setError(err)
+		return
+	}
+	m.unsetError()
+	for i, entry := range m.pickerFiles {
+		if filepath.Join(m.dir, entry) == renamed {
+			m.pickerSelectedIndex = i
+		}
+	}
+	m.updatePickerView()
+}
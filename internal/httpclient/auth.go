@@ -0,0 +1,218 @@
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	flagAuth   = "auth"
+	flagDigest = "digest"
+	flagBearer = "bearer"
+)
+
+// Parse the value of -auth
+func parseCredentials(value string) (username, password string, err error) {
+	username, password, found := strings.Cut(value, ":")
+	if !found || username == "" {
+		return "", "", errors.New("-auth expects user:pass")
+	}
+	return username, password, nil
+}
+
+// Set the Authorization header of the request based on its flags
+// -bearer cannot be combined with -auth or -digest, as each sets the Authorization header
+// Returns a transport that answers Digest challenges when -digest is set
+func authorize(req *http.Request, flags map[string]string, next http.RoundTripper) (http.RoundTripper, error) {
+	if token, prs := flags[flagBearer]; prs {
+		if token == "" {
+			return nil, errors.New("-bearer expects a token")
+		}
+		for _, other := range []string{flagAuth, flagDigest} {
+			if _, prs := flags[other]; prs {
+				return nil, errors.New("-bearer cannot be used with -" + other)
+			}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	value, prs := flags[flagAuth]
+	if !prs {
+		if _, prs := flags[flagDigest]; prs {
+			return nil, errors.New("-digest needs credentials from -auth")
+		}
+		return next, nil
+	}
+	username, password, err := parseCredentials(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, prs := flags[flagDigest]; prs {
+		return &digestTransport{username: username, password: password, next: next}, nil
+	}
+	req.SetBasicAuth(username, password)
+	return next, nil
+}
+
+// Answers HTTP Digest challenges (RFC 7616) by resending requests with credentials
+type digestTransport struct {
+	username string
+	password string
+	next     http.RoundTripper
+
+	// Authorization header of the last answer, for the request section
+	authorization string
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	var challenge map[string]string
+	for _, value := range res.Header.Values("WWW-Authenticate") {
+		if len(value) > 7 && strings.EqualFold(value[:7], "Digest ") {
+			challenge = parseChallenge(value[7:])
+			break
+		}
+	}
+	if challenge == nil {
+		return res, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			// the body was already sent and cannot be sent again
+			return res, nil
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	authorization, err := t.answer(challenge, req.Method, req.URL.RequestURI())
+	if err != nil {
+		if retry.Body != nil {
+			_ = retry.Body.Close()
+		}
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	t.authorization = authorization
+	retry.Header.Set("Authorization", authorization)
+	return t.next.RoundTrip(retry)
+}
+
+// Compute the Authorization header that answers a challenge
+func (t *digestTransport) answer(challenge map[string]string, method, uri string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", errors.New("unsupported digest algorithm " + algorithm)
+	}
+	h := func(s string) string {
+		d := newHash()
+		_, _ = io.WriteString(d, s)
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	var qop string
+	if offered, prs := challenge["qop"]; prs {
+		for _, q := range strings.Split(offered, ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", errors.New("unsupported digest qop " + offered)
+		}
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(b[:])
+	nc := "00000001"
+	realm, nonce := challenge["realm"], challenge["nonce"]
+
+	ha1 := h(t.username + ":" + realm + ":" + t.password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, t.username),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		"algorithm=" + algorithm,
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if qop != "" {
+		params = append(params, "qop="+qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if opaque, prs := challenge["opaque"]; prs {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// Parse the parameters of a challenge like realm="a", qop="auth,auth-int", nonce="b"
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = strings.TrimSpace(s[:comma]), s[comma:]
+		} else {
+			value, s = strings.TrimSpace(s), ""
+		}
+		params[key] = value
+	}
+}
@@ -0,0 +1,239 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+	"github.com/ramitmittal/hitman/internal/template"
+)
+
+var flagForm = "form"
+
+// A request body and the lines that describe it in the request section
+type requestBody struct {
+	reader io.Reader
+
+	// returns a fresh copy of a body that cannot be rewound, e.g. to resend it after a challenge
+	reopen func() (io.ReadCloser, error)
+
+	length      int64
+	contentType string
+	summary     []string
+}
+
+// Returns the value of a request item, reading it from a file for @ operators
+func itemValue(item parser.Item) (string, error) {
+	if !strings.HasSuffix(item.Op, "@") {
+		return item.Value, nil
+	}
+	bytes, err := ioutil.ReadFile(item.Value)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s for %s: %w", item.Value, item.Name, err)
+	}
+	return string(bytes), nil
+}
+
+// Split request items into query parameters and body items
+func splitItems(items []parser.Item) (query []parser.Param, body []parser.Item) {
+	for _, item := range items {
+		if item.Op == "==" {
+			query = append(query, parser.Param{Name: item.Name, Value: item.Value})
+		} else {
+			body = append(body, item)
+		}
+	}
+	return query, body
+}
+
+// Encode request items as a JSON object, keeping the order in which fields were written
+// Fields assigned with := are raw JSON, the rest are strings
+func encodeJSON(items []parser.Item) ([]byte, error) {
+	var names []string
+	fields := map[string][]byte{}
+
+	for _, item := range items {
+		value, err := itemValue(item)
+		if err != nil {
+			return nil, err
+		}
+
+		var field []byte
+		if strings.HasPrefix(item.Op, ":=") {
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, []byte(value)); err != nil {
+				return nil, fmt.Errorf("%s is not valid JSON: %w", item.Name, err)
+			}
+			field = compacted.Bytes()
+		} else if field, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+
+		if _, prs := fields[item.Name]; !prs {
+			names = append(names, item.Name)
+		}
+		fields[item.Name] = field
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(fields[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Encode request items as an application/x-www-form-urlencoded form
+func encodeForm(items []parser.Item) (*requestBody, error) {
+	values := url.Values{}
+	summary := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if strings.HasPrefix(item.Op, ":=") {
+			return nil, fmt.Errorf("%s: raw JSON fields cannot be sent in a form", item.Name)
+		}
+		value, err := itemValue(item)
+		if err != nil {
+			return nil, err
+		}
+		values.Add(item.Name, value)
+		summary = append(summary, item.Name+"="+value)
+	}
+
+	encoded := values.Encode()
+	return &requestBody{
+		reader:      strings.NewReader(encoded),
+		length:      int64(len(encoded)),
+		contentType: "application/x-www-form-urlencoded",
+		summary:     summary,
+	}, nil
+}
+
+// Returns the media type of the Content-Type header, if one was written
+func headerMediaType(headers map[string]string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			mediaType, _, _ := mime.ParseMediaType(v)
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// Build the request body from request items
+// The encoding is picked from flags, then the Content-Type header, and is JSON otherwise
+// Returns nil when the request has no body
+func buildBody(items []parser.Item, headers, flags map[string]string) (*requestBody, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	mediaType := headerMediaType(headers)
+
+	_, multipart := flags[flagMultipart]
+	for _, item := range items {
+		multipart = multipart || item.Op == "<"
+	}
+	if multipart || mediaType == "multipart/form-data" {
+		return encodeMultipart(items)
+	}
+
+	if _, form := flags[flagForm]; form || mediaType == "application/x-www-form-urlencoded" {
+		return encodeForm(items)
+	}
+
+	encoded, err := encodeJSON(items)
+	if err != nil {
+		return nil, err
+	}
+	return &requestBody{
+		reader:      bytes.NewReader(encoded),
+		length:      int64(len(encoded)),
+		contentType: "application/json",
+		summary:     []string{string(encoded)},
+	}, nil
+}
+
+// Resolve the paths of request items that read files
+func (c *Client) resolveItemPaths(items []parser.Item) []parser.Item {
+	resolved := make([]parser.Item, len(items))
+	for i, item := range items {
+		if strings.HasSuffix(item.Op, "@") || item.Op == "<" {
+			// file parts may be followed by ;filename= and ;type= attributes
+			p, attrs, _ := strings.Cut(item.Value, ";")
+			item.Value = c.resolvePath(p)
+			if attrs != "" {
+				item.Value += ";" + attrs
+			}
+		}
+		resolved[i] = item
+	}
+	return resolved
+}
+
+// Build a body that is read from a file, replacing {{variables}} if asked to
+func (c *Client) fileBody(body parser.Body, resolve template.Resolver) (*requestBody, error) {
+	p := c.resolvePath(body.File)
+	contentType := mime.TypeByExtension(filepath.Ext(p))
+
+	if !body.Substitute {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not read body: %w", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("could not read body: %w", err)
+		}
+		return &requestBody{
+			reader: f,
+			reopen: func() (io.ReadCloser, error) {
+				return os.Open(p)
+			},
+			length:      info.Size(),
+			contentType: contentType,
+			summary:     []string{fmt.Sprintf("< %s (%d bytes)", p, info.Size())},
+		}, nil
+	}
+
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read body: %w", err)
+	}
+	expanded, err := template.Expand(string(content), resolve)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return &requestBody{
+		reader:      strings.NewReader(expanded),
+		length:      int64(len(expanded)),
+		contentType: contentType,
+		summary:     []string{fmt.Sprintf("<@ %s (%d bytes after substitution)", p, len(expanded))},
+	}, nil
+}
+
+// Build a body from text written out in the request
+// The text is listed with the request as it is sent
+func textBody(text string) *requestBody {
+	return &requestBody{
+		reader:  strings.NewReader(text),
+		length:  int64(len(text)),
+		summary: strings.Split(text, "\n"),
+	}
+}
@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+	"github.com/ramitmittal/hitman/internal/template"
+)
+
+// How deep named requests may reference each other before it is treated as a loop
+const maxReferenceDepth = 8
+
+// Returns the results of named requests, creating them on first use
+func (c *Client) namedResults() *namedResults {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.named == nil {
+		c.named = &namedResults{results: map[string]*HitResult{}}
+	}
+	return c.named
+}
+
+func (c *Client) setResult(name string, hr *HitResult) {
+	named := c.namedResults()
+	named.mu.Lock()
+	defer named.mu.Unlock()
+
+	named.results[name] = hr
+}
+
+func (c *Client) result(name string) *HitResult {
+	named := c.namedResults()
+	named.mu.Lock()
+	defer named.mu.Unlock()
+
+	return named.results[name]
+}
+
+// Returns the result of the named request, sending it if it has no result yet
+func (c *Client) resultOf(name string, blocks []parser.Block, depth int) (*HitResult, error) {
+	if hr := c.result(name); hr != nil {
+		return hr, nil
+	}
+
+	for _, block := range blocks {
+		if block.Name != name {
+			continue
+		}
+		if depth >= maxReferenceDepth {
+			return nil, errors.New("requests reference each other in a loop")
+		}
+		hr := c.send(block, blocks, depth+1)
+		if hr.Err != nil {
+			return nil, fmt.Errorf("request %s failed: %w", name, hr.Err)
+		}
+		return hr, nil
+	}
+	return nil, errors.New("no request named " + name)
+}
+
+// Returns the value of a reference like login.response.body.$.token or login.response.headers.Location
+// ok is false when the expression is not a reference
+func (c *Client) reference(expr string, blocks []parser.Block, depth int) (value string, ok bool, err error) {
+	parts := strings.SplitN(expr, ".", 4)
+	if len(parts) < 3 || parts[1] != "response" {
+		return "", false, nil
+	}
+
+	hr, err := c.resultOf(parts[0], blocks, depth)
+	if err != nil {
+		return "", true, err
+	}
+
+	switch parts[2] {
+	case "body":
+		if len(parts) == 3 || parts[3] == "*" {
+			return string(hr.rawBody), true, nil
+		}
+		value, err = template.JSONPath(hr.rawBody, parts[3])
+		return value, true, err
+	case "headers":
+		if len(parts) == 3 {
+			return "", true, errors.New("expected a header name after " + expr)
+		}
+		values, prs := hr.header[http.CanonicalHeaderKey(parts[3])]
+		if !prs {
+			return "", true, errors.New("response of " + parts[0] + " has no header " + parts[3])
+		}
+		return strings.Join(values, ", "), true, nil
+	}
+	return "", true, errors.New("expected body or headers in " + expr)
+}
@@ -0,0 +1,274 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of lines in a diff between two results
+const (
+	DiffHeading = iota
+	DiffSame
+	DiffRemoved
+	DiffAdded
+)
+
+// A line of a diff between two results
+type DiffLine struct {
+	Kind int
+	Text string
+}
+
+// bodies with more lines than this are compared as a whole
+const maxDiffLines = 2000
+
+// Returns the method and expanded URL of the request
+func (hr *HitResult) RequestLine() string {
+	for _, line := range hr.RequestHeaders {
+		if !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// Returns the request line and the environment of the request, which identify the request across runs
+func (hr *HitResult) BaselineKey() string {
+	if hr.Environment == "" {
+		return hr.RequestLine()
+	}
+	return hr.RequestLine() + " in " + hr.Environment
+}
+
+// Returns the differences between a result and a baseline result of the same request:
+// the status, headers that were added, removed or changed, and the body
+// JSON bodies are compared by structure, listing the path of each value that changed
+func DiffResults(baseline *HitResult, result *HitResult) []DiffLine {
+	var diff []DiffLine
+
+	diff = append(diff, DiffLine{DiffHeading, "Status"})
+	diff = append(diff, diffValues(firstLine(baseline.ResponseHeaders), firstLine(result.ResponseHeaders))...)
+
+	diff = append(diff, DiffLine{DiffHeading, "Headers"})
+	diff = append(diff, diffHeaders(baseline.ResponseHeaders, result.ResponseHeaders)...)
+
+	diff = append(diff, DiffLine{DiffHeading, "Body"})
+	var before, after interface{}
+	if jsonValue(baseline.rawBody, &before) && jsonValue(result.rawBody, &after) {
+		var changes []DiffLine
+		diffJSON("$", before, after, &changes)
+		if len(changes) == 0 {
+			changes = append(changes, DiffLine{DiffSame, "no changes"})
+		}
+		diff = append(diff, changes...)
+	} else {
+		diff = append(diff, diffText(baseline.ResponseBody, result.ResponseBody)...)
+	}
+	return diff
+}
+
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[0]
+}
+
+// Returns a value as it is when it did not change, and the old and new value otherwise
+func diffValues(before string, after string) []DiffLine {
+	if before == after {
+		return []DiffLine{{DiffSame, after}}
+	}
+	return []DiffLine{{DiffRemoved, before}, {DiffAdded, after}}
+}
+
+// Returns the values of formatted response headers keyed by name
+func headerValues(lines []string) map[string][]string {
+	values := map[string][]string{}
+	for _, line := range lines {
+		if name, value, found := strings.Cut(line, " : "); found {
+			values[name] = append(values[name], value)
+		}
+	}
+	return values
+}
+
+// Returns the headers that were added, removed or changed, and how many stayed the same
+func diffHeaders(before []string, after []string) []DiffLine {
+	if len(before) > 0 {
+		before = before[1:]
+	}
+	if len(after) > 0 {
+		after = after[1:]
+	}
+	old, current := headerValues(before), headerValues(after)
+
+	names := make([]string, 0, len(old)+len(current))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, prs := old[name]; !prs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diff []DiffLine
+	var same int
+	for _, name := range names {
+		if reflect.DeepEqual(old[name], current[name]) {
+			same++
+			continue
+		}
+		for _, value := range old[name] {
+			diff = append(diff, DiffLine{DiffRemoved, name + " : " + value})
+		}
+		for _, value := range current[name] {
+			diff = append(diff, DiffLine{DiffAdded, name + " : " + value})
+		}
+	}
+	if same > 0 {
+		diff = append(diff, DiffLine{DiffSame, fmt.Sprintf("%d unchanged", same)})
+	}
+	return diff
+}
+
+// Decode a JSON body; reports whether it is JSON
+func jsonValue(body []byte, v *interface{}) bool {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return decoder.Decode(v) == nil && !decoder.More()
+}
+
+// JSON keys that can follow a dot in a path
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// Add the values that differ between two JSON values to diff, with their paths
+func diffJSON(path string, before interface{}, after interface{}, diff *[]DiffLine) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(b)+len(a))
+			for key := range b {
+				keys = append(keys, key)
+			}
+			for key := range a {
+				if _, prs := b[key]; !prs {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				keyPath := path + "." + key
+				if !identifier.MatchString(key) {
+					quoted, _ := json.Marshal(key)
+					keyPath = path + "[" + string(quoted) + "]"
+				}
+				oldValue, inBefore := b[key]
+				newValue, inAfter := a[key]
+				switch {
+				case !inAfter:
+					*diff = append(*diff, DiffLine{DiffRemoved, keyPath + ": " + compactJSON(oldValue)})
+				case !inBefore:
+					*diff = append(*diff, DiffLine{DiffAdded, keyPath + ": " + compactJSON(newValue)})
+				default:
+					diffJSON(keyPath, oldValue, newValue, diff)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			for i := 0; i < len(b) || i < len(a); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(a):
+					*diff = append(*diff, DiffLine{DiffRemoved, itemPath + ": " + compactJSON(b[i])})
+				case i >= len(b):
+					*diff = append(*diff, DiffLine{DiffAdded, itemPath + ": " + compactJSON(a[i])})
+				default:
+					diffJSON(itemPath, b[i], a[i], diff)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*diff = append(*diff,
+			DiffLine{DiffRemoved, path + ": " + compactJSON(before)},
+			DiffLine{DiffAdded, path + ": " + compactJSON(after)},
+		)
+	}
+}
+
+func compactJSON(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
+
+// Returns a line by line diff of two texts
+// Long texts are shown as removed and added as a whole
+func diffText(before string, after string) []DiffLine {
+	if before == after {
+		return []DiffLine{{DiffSame, "no changes"}}
+	}
+	old, current := strings.Split(before, "\n"), strings.Split(after, "\n")
+	if len(old) > maxDiffLines || len(current) > maxDiffLines {
+		return []DiffLine{{DiffRemoved, before}, {DiffAdded, after}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of old[i:] and current[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(current)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(current) - 1; j >= 0; j-- {
+			if old[i] == current[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []DiffLine
+	i, j := 0, 0
+	for i < len(old) && j < len(current) {
+		switch {
+		case old[i] == current[j]:
+			diff = append(diff, DiffLine{DiffSame, old[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{DiffRemoved, old[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffAdded, current[j]})
+			j++
+		}
+	}
+	for ; i < len(old); i++ {
+		diff = append(diff, DiffLine{DiffRemoved, old[i]})
+	}
+	for ; j < len(current); j++ {
+		diff = append(diff, DiffLine{DiffAdded, current[j]})
+	}
+	return diff
+}
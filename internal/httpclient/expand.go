@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+	"github.com/ramitmittal/hitman/internal/store"
+	"github.com/ramitmittal/hitman/internal/template"
+)
+
+// Returns the variables defined with @name = value lines in the document of a block
+func fileVariables(block parser.Block, blocks []parser.Block) map[string]string {
+	if blocks == nil {
+		return parser.FileVariables(block.Text)
+	}
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		texts[i] = b.Text
+	}
+	return parser.FileVariables(strings.Join(texts, "\n"))
+}
+
+// Records the expansions of a request as notes for the request section
+type expansionNotes struct {
+	notes *[]string
+
+	// header or flag that the values expanded next are used in, like "Authorization" or "-bearer"
+	// Notes name it so that values of sensitive ones can be hidden
+	in string
+}
+
+// Record the value that an expression expanded to
+func (n *expansionNotes) add(expr string, value string) {
+	if n == nil {
+		return
+	}
+	if n.in == "" {
+		*n.notes = append(*n.notes, "# {{"+expr+"}} = "+value)
+	} else {
+		*n.notes = append(*n.notes, "# {{"+expr+"}} in "+n.in+" : "+value)
+	}
+}
+
+// Returns the resolver for {{expressions}} in a request
+// Variables of the file come before variables of the environment, and may use {{expressions}} themselves
+// Variables and secrets are loaded on first use and every expansion is recorded in notes, with secrets hidden
+// Nothing is recorded when notes is nil, as for the credentials of the OAuth2 and HMAC configuration
+func (c *Client) resolver(flags map[string]string, notes *expansionNotes, blocks []parser.Block, fileVars map[string]string, depth int) template.Resolver {
+	var variables map[string]string
+	var resolver template.Resolver
+	expanding := map[string]bool{}
+
+	lookup := func(expr string) (string, error) {
+		if value, ok, err := c.reference(expr, blocks, depth); ok {
+			return value, err
+		}
+		if value, prs := fileVars[expr]; prs {
+			if expanding[expr] {
+				return "", errors.New("variable " + expr + " refers to itself")
+			}
+			expanding[expr] = true
+			defer delete(expanding, expr)
+			return template.Expand(value, resolver)
+		}
+		if variables == nil {
+			var err error
+			if variables, err = c.variables(flags); err != nil {
+				return "", err
+			}
+		}
+		return template.Variables(variables)(expr)
+	}
+
+	var secrets map[string]string
+	resolve := template.Builtins(lookup)
+	resolver = func(expr string) (string, error) {
+		if args := strings.Fields(expr); len(args) > 0 && args[0] == "$secret" {
+			if len(args) != 2 {
+				return "", errors.New("$secret expects the name of a secret")
+			}
+			if secrets == nil {
+				var err error
+				if secrets, err = store.LoadSecrets(); err != nil {
+					return "", err
+				}
+			}
+			value, prs := secrets[args[1]]
+			if !prs {
+				return "", errors.New("unknown secret " + args[1])
+			}
+			if notes != nil {
+				*notes.notes = append(*notes.notes, "# {{"+expr+"}} = "+Redacted)
+			}
+			return value, nil
+		}
+
+		value, err := resolve(expr)
+		if err == nil {
+			notes.add(expr, value)
+		}
+		return value, err
+	}
+	return resolver
+}
+
+// Replace {{expressions}} in every part of a parsed request
+// This runs after parsing so that expanded values never change how the request is parsed
+// Values expanded in headers and flags are recorded in notes with the header or flag they are used in
+func expandRequest(r *parser.Result, resolve template.Resolver, notes *expansionNotes) error {
+	var err error
+	in := func(target string) {
+		if notes != nil {
+			notes.in = target
+		}
+	}
+	defer in("")
+	expand := func(s string) string {
+		if err != nil {
+			return s
+		}
+		var expanded string
+		expanded, err = template.Expand(s, resolve)
+		return expanded
+	}
+
+	r.Method = expand(r.Method)
+	r.Url = expand(r.Url)
+	for i := range r.Query {
+		r.Query[i].Name = expand(r.Query[i].Name)
+		r.Query[i].Value = expand(r.Query[i].Value)
+	}
+
+	headers := make(map[string]string, len(r.Headers))
+	for k, v := range r.Headers {
+		in("")
+		name := expand(k)
+		in(name)
+		headers[name] = expand(v)
+	}
+	in("")
+	r.Headers = headers
+
+	for i := range r.Items {
+		r.Items[i].Name = expand(r.Items[i].Name)
+		r.Items[i].Value = expand(r.Items[i].Value)
+	}
+	r.Body.File = expand(r.Body.File)
+	r.Body.Text = expand(r.Body.Text)
+
+	for k, v := range r.Flags {
+		in("-" + k)
+		r.Flags[k] = expand(v)
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/store"
+	"github.com/ramitmittal/hitman/internal/template"
+)
+
+var flagHMAC = "hmac"
+
+// Returns the config with {{expressions}} in its values replaced
+// Format is left alone because its placeholders are filled in after signing
+func expandHMACConfig(config store.HMACConfig, resolve template.Resolver) (store.HMACConfig, error) {
+	var err error
+	values := []*string{&config.Algorithm, &config.Secret, &config.Encoding, &config.Header, &config.TimestampHeader}
+	parts := append([]string{}, config.Parts...)
+	for i := range parts {
+		values = append(values, &parts[i])
+	}
+	for _, value := range values {
+		if *value, err = template.Expand(*value, resolve); err != nil {
+			return config, fmt.Errorf("hmac: %w", err)
+		}
+	}
+	config.Parts = parts
+	return config, nil
+}
+
+// Returns the hash function that an algorithm name stands for
+func hmacHash(algorithm string) (func() hash.Hash, string, error) {
+	switch strings.TrimPrefix(strings.ToLower(algorithm), "hmac-") {
+	case "", "sha256", "sha-256":
+		return sha256.New, "HMAC-SHA256", nil
+	case "sha1", "sha-1":
+		return sha1.New, "HMAC-SHA1", nil
+	case "sha512", "sha-512":
+		return sha512.New, "HMAC-SHA512", nil
+	}
+	return nil, "", errors.New("hmac: unsupported algorithm " + algorithm)
+}
+
+// Returns the body of a request without consuming it
+func requestBodyBytes(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("the request body cannot be read twice")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Returns the string that is signed for the request
+func hmacMessage(req *http.Request, config store.HMACConfig, timestamp string) (string, error) {
+	if len(config.Parts) == 0 {
+		return "", errors.New("hmac: parts must list what is signed")
+	}
+
+	values := make([]string, 0, len(config.Parts))
+	for _, part := range config.Parts {
+		kind, arg, _ := strings.Cut(part, ":")
+		switch kind {
+		case "timestamp":
+			values = append(values, timestamp)
+		case "method":
+			values = append(values, req.Method)
+		case "path":
+			values = append(values, req.URL.RequestURI())
+		case "body":
+			body, err := requestBodyBytes(req)
+			if err != nil {
+				return "", fmt.Errorf("hmac: %w", err)
+			}
+			values = append(values, string(body))
+		case "header":
+			values = append(values, req.Header.Get(arg))
+		case "literal":
+			values = append(values, arg)
+		default:
+			return "", errors.New("hmac: unknown part " + part)
+		}
+	}
+
+	separator := "."
+	if config.Separator != nil {
+		separator = *config.Separator
+	}
+	return strings.Join(values, separator), nil
+}
+
+// Set a signature header computed from the parts of the request that the config names
+// Returns notes with the signed string for the request section
+func signHMAC(req *http.Request, config store.HMACConfig) ([]string, error) {
+	newHash, name, err := hmacHash(config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if config.Header == "" {
+		return nil, errors.New("hmac: header must name the signature header")
+	}
+	if config.Secret == "" {
+		return nil, errors.New("hmac: secret is empty")
+	}
+
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	if config.TimestampHeader != "" {
+		req.Header.Set(config.TimestampHeader, timestamp)
+	}
+
+	message, err := hmacMessage(req, config, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(newHash, []byte(config.Secret))
+	_, _ = io.WriteString(mac, message)
+
+	var signature string
+	switch strings.ToLower(config.Encoding) {
+	case "", "hex":
+		signature = hex.EncodeToString(mac.Sum(nil))
+	case "base64":
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		return nil, errors.New("hmac: unsupported encoding " + config.Encoding)
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "{signature}"
+	}
+	req.Header.Set(config.Header, strings.NewReplacer("{signature}", signature, "{timestamp}", timestamp).Replace(format))
+
+	notes := []string{fmt.Sprintf("# %s string to sign for %s", name, config.Header)}
+	for _, line := range strings.Split(message, "\n") {
+		notes = append(notes, "#   "+line)
+	}
+	return notes, nil
+}
+
+// Sign the request with the HMAC settings of its environment when -hmac is set
+// Must run after the body and headers of the request are set
+func (c *Client) hmacSign(req *http.Request, flags map[string]string, resolve template.Resolver) ([]string, error) {
+	if _, prs := flags[flagHMAC]; !prs {
+		return nil, nil
+	}
+
+	env, err := c.environment(flags)
+	if err != nil {
+		return nil, err
+	}
+	if env.HMAC == nil {
+		return nil, errors.New("-hmac needs \"hmac\" settings in the environment")
+	}
+	config, err := expandHMACConfig(*env.HMAC, resolve)
+	if err != nil {
+		return nil, err
+	}
+	return signHMAC(req, config)
+}
@@ -1,14 +1,17 @@
 package httpclient
 
 import (
-	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/ramitmittal/hitman/internal/parser"
+	"github.com/ramitmittal/hitman/internal/store"
 )
 
 type HitResult struct {
@@ -16,12 +19,25 @@ type HitResult struct {
 	RequestHeaders  []string
 	ResponseHeaders []string
 	ResponseBody    string
+
+	// address of the server that the request was sent to
+	RemoteAddr string
+
+	// environment that the request was sent in, if any
+	Environment string
+
+	// OAuth2 token request made before this request, if one was needed
+	TokenExchange *HitResult
+
+	// response headers and body as received, for requests that reference this one
+	header  http.Header
+	rawBody []byte
 }
 
-func formatRequest(req *http.Request) []string {
-	reqHeaders := []string{
+func formatRequest(req *http.Request, query []parser.Param) []string {
+	reqHeaders := append([]string{
 		req.Method + " " + req.URL.String(),
-	}
+	}, formatQuery(query)...)
 	for h, v := range req.Header {
 		for _, vv := range v {
 			reqHeaders = append(reqHeaders, h+" : "+vv)
@@ -55,63 +71,369 @@ func formatResponseBody(body []byte) string {
 	return string(body)
 }
 
+// Format the cookies that the session will attach to the request
+func formatCookies(cookies []*http.Cookie) string {
+	pairs := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		pairs = append(pairs, c.String())
+	}
+	return "Cookie : " + strings.Join(pairs, "; ")
+}
+
 var (
 	flagInsecureSkipVerify = "insecure"
 	flagFollowRedirects    = "location"
+	flagSession            = "session"
+	flagNoCookies          = "no-cookies"
 )
 
+// Client holds settings that apply to every request
+type Client struct {
+	// scheme for URLs of remote hosts without one, https when empty
+	// local and private addresses always use http
+	DefaultScheme string
+
+	// directory that relative file paths are resolved against
+	BaseDir string
+
+	// environment whose variables are used when -env is not set
+	Environment string
+
+	// root of the project that requests belong to, if any
+	// environments defined in the project are used over the global ones
+	ProjectDir string
+
+	// true when Environment is used even if -env picks another one, as when comparing environments
+	forceEnvironment bool
+
+	// cookie sessions shared by the requests of a comparison and saved once it is done
+	// nil when every request loads and saves its session itself
+	sessions *sharedSessions
+
+	// results of named requests, for requests that reference them
+	// shared with the copies made by InDir
+	mu    sync.Mutex
+	named *namedResults
+}
+
+// Results of named requests keyed by name
+type namedResults struct {
+	mu      sync.Mutex
+	results map[string]*HitResult
+}
+
+// Returns a copy of the client that resolves relative file paths against dir
+// The copy shares the results of named requests with the client, so a client that is in use
+// is replaced with a copy instead of being changed while requests are sent with it
+func (c *Client) InDir(dir string) *Client {
+	return &Client{
+		DefaultScheme:    c.DefaultScheme,
+		BaseDir:          dir,
+		Environment:      c.Environment,
+		ProjectDir:       c.ProjectDir,
+		forceEnvironment: c.forceEnvironment,
+		sessions:         c.sessions,
+		named:            c.namedResults(),
+	}
+}
+
+// Perform an HTTP request based on the command text using default settings
+func Hit(text string) *HitResult {
+	return (&Client{}).Hit(text)
+}
+
 // Perform an HTTP request based on the command text
-func Hit(text string) (hr *HitResult) {
+func (c *Client) Hit(text string) *HitResult {
+	return c.send(parser.Block{Text: text}, nil, 0)
+}
+
+// Perform the HTTP request in the block of the document that contains the line
+func (c *Client) Send(document string, line int) *HitResult {
+	blocks := parser.SplitBlocks(document)
+	return c.send(parser.BlockAt(blocks, line), blocks, 0)
+}
+
+// Cookie sessions loaded once for requests that are sent at the same time
+type sharedSessions struct {
+	mu     sync.Mutex
+	loaded map[string]*store.Session
+}
+
+// Returns the session with the provided name, loading it on first use
+func (s *sharedSessions) load(name string) (*store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, prs := s.loaded[name]; prs {
+		return session, nil
+	}
+	session, err := store.LoadSession(name)
+	if err != nil {
+		return nil, err
+	}
+	s.loaded[name] = session
+	return session, nil
+}
+
+// Save every loaded session
+func (s *sharedSessions) save() error {
+	for name, session := range s.loaded {
+		if err := store.SaveSession(session); err != nil {
+			return fmt.Errorf("could not save session %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Returns the cookie session with the provided name, shared with the other requests of a comparison if any
+func (c *Client) loadSession(name string) (*store.Session, error) {
+	if c.sessions != nil {
+		return c.sessions.load(name)
+	}
+	return store.LoadSession(name)
+}
+
+// Perform the HTTP request in the block of the document that contains the line in several environments at once
+// The environments are used instead of the one picked by -env, and named requests that the block references
+// are sent again in each of them
+// Cookies of all environments go to the same sessions, which are saved once every request is done
+// Returns the results in the order of the environments
+func (c *Client) SendInEnvironments(document string, line int, environments ...string) []*HitResult {
+	results := make([]*HitResult, len(environments))
+	sessions := &sharedSessions{loaded: map[string]*store.Session{}}
+
+	var wg sync.WaitGroup
+	for i, env := range environments {
+		wg.Add(1)
+		go func(i int, env string) {
+			defer wg.Done()
+			ec := &Client{
+				DefaultScheme:    c.DefaultScheme,
+				BaseDir:          c.BaseDir,
+				Environment:      env,
+				ProjectDir:       c.ProjectDir,
+				forceEnvironment: true,
+				sessions:         sessions,
+			}
+			results[i] = ec.Send(document, line)
+		}(i, env)
+	}
+	wg.Wait()
+
+	if err := sessions.save(); err != nil {
+		for _, hr := range results {
+			if hr.Err == nil {
+				hr.Err = err
+			}
+		}
+	}
+	return results
+}
+
+// Perform the HTTP request in a block
+// Named requests that the block references are sent first if they have no result yet
+func (c *Client) send(block parser.Block, blocks []parser.Block, depth int) (hr *HitResult) {
 	hr = &HitResult{}
 
-	parserResult, err := parser.Parse([]byte(text))
+	parserResult, err := parser.ParseRequest(block.Text)
 	if err != nil {
 		hr.Err = errors.New("please enter a valid query")
 		return
 	}
 
-	client := http.Client{}
+	if block.Name != "" {
+		defer func() {
+			if hr.Err == nil {
+				c.setResult(block.Name, hr)
+			}
+		}()
+	}
 
-	if _, prs := parserResult.Flags[flagInsecureSkipVerify]; prs {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		}
+	var notes []string
+	for _, warning := range parserResult.Warnings {
+		notes = append(notes, "# Warning: "+warning)
+	}
+
+	hr.Environment = c.environmentName(parserResult.Flags)
+	fileVars := fileVariables(block, blocks)
+	expansions := &expansionNotes{notes: &notes}
+	resolve := c.resolver(parserResult.Flags, expansions, blocks, fileVars, depth)
+	if err := expandRequest(&parserResult, resolve, expansions); err != nil {
+		hr.Err = err
+		return
 	}
+
+	url := parserResult.Url
+	if strings.HasPrefix(url, unixScheme) {
+		parserResult.Flags[flagUnixSocket], url = splitUnixURL(url)
+	}
+	if _, prs := parserResult.Flags[flagUnixSocket]; prs && parserResult.Flags[flagUnixSocket] == "" {
+		hr.Err = errors.New("-unix-socket expects the path of a socket")
+		return
+	}
+
+	transport, err := newTransport(parserResult.Flags)
+	if err != nil {
+		hr.Err = err
+		return
+	}
+	client := http.Client{
+		Transport: transport,
+	}
+
 	if _, prs := parserResult.Flags[flagFollowRedirects]; !prs {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 	}
 
-	url := parserResult.Url
-	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
-		url = "https://" + url
+	var session *store.Session
+	if _, prs := parserResult.Flags[flagNoCookies]; !prs {
+		name := parserResult.Flags[flagSession]
+		if name == "" {
+			name = store.DefaultSession
+		}
+		if session, err = c.loadSession(name); err != nil {
+			hr.Err = err
+			return
+		}
+		client.Jar = session
+
+		if c.sessions == nil {
+			defer func() {
+				if err := store.SaveSession(session); err != nil && hr.Err == nil {
+					hr.Err = fmt.Errorf("could not save session %s: %w", name, err)
+				}
+			}()
+		}
+	}
+
+	if normalized, err := c.normalizeURL(url, parserResult.Flags); err != nil {
+		hr.Err = err
+		return
+	} else if normalized != url {
+		notes = append(notes, "# URL rewritten from "+url)
+		url = normalized
+	}
+
+	queryItems, bodyItems := splitItems(parserResult.Items)
+	query := append(parserResult.Query, queryItems...)
+
+	bodyItems = c.resolveItemPaths(bodyItems)
+
+	var reqBody *requestBody
+	if parserResult.Body.File != "" {
+		if len(bodyItems) > 0 {
+			hr.Err = errors.New("a body file cannot be combined with request items")
+			return
+		}
+		reqBody, err = c.fileBody(parserResult.Body, resolve)
+	} else if parserResult.Body.Text != "" {
+		reqBody = textBody(parserResult.Body.Text)
+	} else {
+		reqBody, err = buildBody(bodyItems, parserResult.Headers, parserResult.Flags)
+	}
+	if err != nil {
+		hr.Err = err
+		return
 	}
 
-	req, err := http.NewRequest(parserResult.Method, url, nil)
+	var bodyReader io.Reader
+	if reqBody != nil {
+		bodyReader = reqBody.reader
+	}
+	req, err := http.NewRequest(parserResult.Method, url, bodyReader)
 	if err != nil {
+		if closer, ok := bodyReader.(io.Closer); ok {
+			_ = closer.Close()
+		}
 		hr.Err = err
 		return
 	}
+	// the body is closed by client.Do, or here when the request fails before it is sent,
+	// which stops the writer of a multipart body and closes a body file
+	sent := false
+	defer func() {
+		if !sent && req.Body != nil {
+			_ = req.Body.Close()
+		}
+	}()
+	if reqBody != nil {
+		req.ContentLength = reqBody.length
+		if reqBody.reopen != nil {
+			req.GetBody = reqBody.reopen
+		}
+	}
+	addQuery(req.URL, query)
 	for k, v := range parserResult.Headers {
 		req.Header.Add(k, v)
 	}
+	if reqBody != nil && reqBody.contentType != "" && (req.Header.Get("Content-Type") == "" || strings.HasPrefix(reqBody.contentType, "multipart/")) {
+		// a multipart Content-Type must carry the boundary that the body was written with
+		req.Header.Set("Content-Type", reqBody.contentType)
+	}
 	if req.Header.Get("Host") != "" {
 		// Go httpClient treats Host header specially
 		// Set it on the request directly
 		req.Host = req.Header.Get("Host")
 	}
+	if client.Transport, err = authorize(req, parserResult.Flags, client.Transport); err != nil {
+		hr.Err = err
+		return
+	}
+	// credentials in the OAuth2 and HMAC configuration are not recorded in notes
+	credentials := c.resolver(parserResult.Flags, nil, blocks, fileVars, depth)
+	if note, err := c.authorizeOAuth2(req, parserResult.Flags, credentials, hr); err != nil {
+		hr.Err = err
+		return
+	} else if note != "" {
+		notes = append(notes, note)
+	}
+	if signed, err := c.hmacSign(req, parserResult.Flags, credentials); err != nil {
+		hr.Err = err
+		return
+	} else {
+		notes = append(notes, signed...)
+	}
+	if signed, err := awsSign(req, parserResult.Flags); err != nil {
+		hr.Err = err
+		return
+	} else {
+		notes = append(notes, signed...)
+	}
 
-	hr.RequestHeaders = formatRequest(req)
+	hr.RequestHeaders = formatRequest(req, query)
+	if session != nil {
+		if cookies := session.Cookies(req.URL); len(cookies) > 0 {
+			hr.RequestHeaders = append(hr.RequestHeaders, formatCookies(cookies))
+		}
+	}
+	if reqBody != nil {
+		hr.RequestHeaders = append(hr.RequestHeaders, reqBody.summary...)
+	}
+	hr.RequestHeaders = append(hr.RequestHeaders, notes...)
 
+	// record the address of the connection that served the response
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			hr.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+	}))
+
+	sent = true
 	res, err := client.Do(req)
+	if digest, ok := client.Transport.(*digestTransport); ok && digest.authorization != "" {
+		hr.RequestHeaders = append(hr.RequestHeaders, "# Answered Digest challenge with Authorization : "+digest.authorization)
+	}
+	if hr.RemoteAddr != "" {
+		hr.RequestHeaders = append(hr.RequestHeaders, "# Connected to "+hr.RemoteAddr)
+	}
 	if err != nil {
 		hr.Err = err
 		return
 	}
 	hr.ResponseHeaders = formatResponseHeaders(res)
+	hr.header = res.Header
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -120,6 +442,7 @@ func Hit(text string) (hr *HitResult) {
 	}
 	_ = res.Body.Close()
 
+	hr.rawBody = body
 	hr.ResponseBody = formatResponseBody(body)
 	return
 }
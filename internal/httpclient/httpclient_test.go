@@ -1,13 +1,43 @@
 package httpclient
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ramitmittal/hitman/internal/store"
 )
 
+// Use home as the home directory with default config and state directories in it
+func setHome(t *testing.T, home string) {
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+}
+
+func writeEnvironments(environments string) {
+	file := store.ConfigPath("environments.json")
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(file, []byte(environments), 0644); err != nil {
+		panic(err)
+	}
+}
+
 func TestHttpClient(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		customHeader := r.Header["X-Custom-Header"]
@@ -118,3 +148,1197 @@ func TestRedirects(t *testing.T) {
 		})
 	}
 }
+
+func TestSessions(t *testing.T) {
+	setHome(t, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret", Path: "/"})
+			w.WriteHeader(http.StatusOK)
+		} else if c, err := r.Cookie("sid"); err == nil && c.Value == "secret" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		name             string
+		input            string
+		expectedResponse string
+	}{
+		{"Not logged in", `GET "%s/me"`, "401 Unauthorized"},
+		{"Log in", `GET "%s/login"`, "200 OK"},
+		{"Default session remembers cookies", `GET "%s/me"`, "200 OK"},
+		{"Cookies can be disabled", `GET "%s/me" -no-cookies`, "401 Unauthorized"},
+		{"Named sessions are separate", `GET "%s/me" -session other`, "401 Unauthorized"},
+		{"Log in to named session", `GET "%s/login" -session other`, "200 OK"},
+		{"Named session remembers cookies", `GET "%s/me" -session other`, "200 OK"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Fail()
+			} else if hr.ResponseHeaders[0] != test.expectedResponse {
+				t.Log(hr.ResponseHeaders[0])
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "pinned.test:"+r.URL.Query().Get("port") {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	var tests = []struct {
+		name    string
+		input   string
+		success bool
+	}{
+		{"Pinned host", `GET "http://pinned.test:%[1]d/?port=%[1]d" -resolve pinned.test:%[1]d:127.0.0.1`, true},
+		{"Pinned host over IPv4", `GET "http://pinned.test:%[1]d/?port=%[1]d" -resolve pinned.test:%[1]d:127.0.0.1 -ipv4`, true},
+		{"Invalid address", `GET "http://pinned.test:%[1]d/" -resolve pinned.test:%[1]d:localhost`, false},
+		{"Conflicting address families", `GET "http://pinned.test:%[1]d/" -ipv4 -ipv6`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hr := Hit(fmt.Sprintf(test.input, port))
+			if !test.success {
+				if hr.Err == nil {
+					t.Fail()
+				}
+			} else if hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseHeaders[0] != "200 OK" {
+				t.Fail()
+			} else if hr.RemoteAddr != fmt.Sprintf("127.0.0.1:%d", port) {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "hitman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("unix sockets are not supported")
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.41/info" {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	var tests = []struct {
+		name  string
+		input string
+	}{
+		{"Socket flag", `GET localhost/v1.41/info -unix-socket %s`},
+		{"Socket flag with scheme", `GET "http://docker/v1.41/info" -unix-socket %s`},
+		{"Socket URL", `GET "unix:%s:/v1.41/info"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, socket)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseHeaders[0] != "200 OK" {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestDefaultScheme(t *testing.T) {
+	var tests = []struct {
+		name          string
+		defaultScheme string
+		input         string
+		expectedURL   string
+	}{
+		{"Remote hosts use https", "", "example.com/health", "https://example.com/health"},
+		{"localhost uses http", "", "localhost:8080/health", "http://localhost:8080/health"},
+		{"Loopback addresses use http", "", "127.0.0.1:8080/health", "http://127.0.0.1:8080/health"},
+		{"Private addresses use http", "", "192.168.1.10/health", "http://192.168.1.10/health"},
+		{"Configured scheme is used for remote hosts", "http", "example.com/health", "http://example.com/health"},
+		{"Local hosts ignore the configured scheme", "https", "localhost:8080/health", "http://localhost:8080/health"},
+		{"Explicit scheme is kept", "", "https://localhost:8080/health", "https://localhost:8080/health"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{DefaultScheme: test.defaultScheme}
+			if u, err := c.normalizeURL(test.input, map[string]string{}); err != nil {
+				t.Fail()
+			} else if u != test.expectedURL {
+				t.Log(u)
+				t.Fail()
+			}
+		})
+	}
+
+	c := &Client{}
+	if u, err := c.normalizeURL("example.com", map[string]string{"scheme": "http"}); err != nil || u != "http://example.com" {
+		t.Fail()
+	}
+	if _, err := c.normalizeURL("example.com", map[string]string{"scheme": "ftp"}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("page") != "2" || q.Get("q") != "hello world & more" || q.Get("redirect") != "https://example.com/?a=b" {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	input := fmt.Sprintf(`GET "%s/search?page=2"
+?q="hello world & more"
+&redirect=https://example.com/?a=b`, server.URL)
+
+	if hr := Hit(input); hr.Err != nil {
+		t.Fail()
+	} else if hr.ResponseHeaders[0] != "200 OK" {
+		t.Fail()
+	} else if hr.RequestHeaders[1] != `?q=hello world & more` {
+		t.Fail()
+	}
+}
+
+func TestRequestItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(file, []byte(`["a", "b"]`), 0644); err != nil {
+		panic(err)
+	}
+
+	var tests = []struct {
+		name        string
+		input       string
+		contentType string
+		body        string
+	}{
+		{
+			"JSON fields",
+			`POST "%s" name=hitman stars:=42 draft:=false q==search`,
+			"application/json",
+			`{"name":"hitman","stars":42,"draft":false}`,
+		},
+		{
+			"JSON from file",
+			`POST "%s" tags:=@` + file,
+			"application/json",
+			`{"tags":["a","b"]}`,
+		},
+		{
+			"Form fields",
+			`POST "%s" user=alice password="p@ss word" q==search -form`,
+			"application/x-www-form-urlencoded",
+			`password=p%%40ss+word&user=alice`,
+		},
+		{
+			"Form from content type",
+			"POST \"%s\" Content-Type: application/x-www-form-urlencoded\ngrant_type=client_credentials scope=\"read write\"",
+			"application/x-www-form-urlencoded",
+			`grant_type=client_credentials&scope=read+write`,
+		},
+		{
+			"Repeated form fields",
+			`POST "%s" tag=a tag=b -form`,
+			"application/x-www-form-urlencoded",
+			`tag=a&tag=b`,
+		},
+		{
+			"Explicit content type",
+			"POST \"%s\" Content-Type: application/vnd.api+json\nname=hitman",
+			"application/vnd.api+json",
+			`{"name":"hitman"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != strings.ReplaceAll(test.body, "%%", "%") {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			} else if !contains(hr.ResponseHeaders, "Content-Type : "+test.contentType) {
+				t.Fail()
+			}
+		})
+	}
+
+	if hr := Hit(fmt.Sprintf(`POST "%s" count:=forty-two`, server.URL)); hr.Err == nil {
+		t.Fail()
+	}
+	if hr := Hit(fmt.Sprintf(`POST "%s" count:=42 -form`, server.URL)); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 || len(r.TransferEncoding) > 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		_, _ = fmt.Fprintf(w, "%s %s %s %s", r.FormValue("title"), header.Filename, header.Header.Get("Content-Type"), content)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "avatar.png")
+	if err := os.WriteFile(file, []byte("not really a png"), 0644); err != nil {
+		panic(err)
+	}
+
+	var tests = []struct {
+		name  string
+		input string
+		body  string
+	}{
+		{"File part", `POST "%s" title=hello avatar < %s`, "hello avatar.png image/png not really a png"},
+		{"File part attributes", `POST "%s" title=hello avatar<%s;filename=me.png;type=image/x-icon`, "hello me.png image/x-icon not really a png"},
+		{"Multipart from content type", "POST \"%s\" Content-Type: multipart/form-data\ntitle=hello avatar@%s", "hello avatar.png image/png not really a png"},
+		{"httpie-style file part", `POST "%s" title=hello avatar@%s -multipart`, "hello avatar.png image/png not really a png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL, file)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != test.body {
+				t.Log(hr.ResponseHeaders[0], hr.ResponseBody)
+				t.Fail()
+			}
+		})
+	}
+
+	if hr := Hit(fmt.Sprintf(`POST "%s" avatar < ./does-not-exist.png`, server.URL)); hr.Err == nil {
+		t.Fail()
+	}
+
+	// requests that fail before they are sent stop writing their bodies
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		if hr := Hit(fmt.Sprintf(`POST "%s" avatar < %s -bearer`, server.URL, file)); hr.Err == nil {
+			t.FailNow()
+		}
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Log(before, after)
+		t.Fail()
+	}
+}
+
+func TestBodyFile(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "payloads"), 0755); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "payloads", "order.json"), []byte(`{"host": "{{host}}"}`), 0644); err != nil {
+		panic(err)
+	}
+	writeEnvironments(`{"staging": {"host": "staging.example.com"}}`)
+
+	c := &Client{BaseDir: dir}
+
+	var tests = []struct {
+		name  string
+		input string
+		body  string
+	}{
+		{"Raw body file", `POST "%s" < ./payloads/order.json`, `{"host": "{{host}}"}`},
+		{"Body file with variables", `POST "%s" <@ ./payloads/order.json -env staging`, `{"host": "staging.example.com"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := c.Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != test.body {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			} else if !contains(hr.ResponseHeaders, "Content-Type : application/json") {
+				t.Fail()
+			}
+		})
+	}
+
+	var invalid = []struct {
+		name  string
+		input string
+	}{
+		{"Missing body file", `POST "%s" < ./payloads/missing.json`},
+		{"Unknown environment", `POST "%s" <@ ./payloads/order.json -env production`},
+		{"Unknown variable", `POST "%s" <@ ./payloads/order.json`},
+		{"Body file and items", `POST "%s" name=hitman < ./payloads/order.json`},
+	}
+
+	for _, test := range invalid {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := c.Hit(fmt.Sprintf(test.input, server.URL)); hr.Err == nil {
+				t.Fail()
+			}
+		})
+	}
+
+}
+
+func TestExpansion(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.Header.Get("Authorization"), body)
+	}))
+	defer server.Close()
+
+	writeEnvironments(fmt.Sprintf(`{"local": {"host": "%s"}}`, server.URL))
+
+	c := &Client{Environment: "local"}
+	input := `POST "{{host}}/orders/{{$randomInt 7 8}}"
+Authorization: "Basic {{$base64 "user:pass"}}"
+id={{$randomInt 42 43}}`
+
+	if hr := c.Hit(input); hr.Err != nil {
+		t.Log(hr.Err)
+		t.Fail()
+	} else if hr.ResponseBody != `/orders/7 Basic dXNlcjpwYXNz {"id":"42"}` {
+		t.Log(hr.ResponseBody)
+		t.Fail()
+	} else if !contains(hr.RequestHeaders, "# {{$randomInt 42 43}} = 42") {
+		t.Log(hr.RequestHeaders)
+		t.Fail()
+	} else if !contains(hr.RequestHeaders, `# {{$base64 "user:pass"}} in Authorization : dXNlcjpwYXNz`) {
+		t.Log(hr.RequestHeaders)
+		t.Fail()
+	} else if redacted := RedactResult(hr.RequestHeaders, nil); strings.Contains(strings.Join(redacted, "\n"), "dXNlcjpwYXNz") {
+		t.Log(redacted)
+		t.Fail()
+	}
+
+	if hr := c.Hit(`GET "{{host}}/{{$nope}}"`); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func TestChaining(t *testing.T) {
+	var logins int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			logins++
+			w.Header().Set("Location", "/me")
+			_, _ = fmt.Fprintf(w, `{"access_token": "token-%d"}`, logins)
+		case "/me":
+			_, _ = fmt.Fprintf(w, "%s %s", r.Header.Get("Authorization"), r.URL.Query().Get("next"))
+		}
+	}))
+	defer server.Close()
+
+	document := fmt.Sprintf(`# @name login
+POST "%[1]s/login" user=alice -no-cookies
+###
+GET "%[1]s/me"
+?next={{login.response.headers.Location}}
+Authorization: "Bearer {{login.response.body.$.access_token}}"
+-no-cookies
+###
+GET "%[1]s/me" Authorization: "{{missing.response.body.$.token}}"`, server.URL)
+
+	c := &Client{}
+
+	// login is sent on demand the first time
+	if hr := c.Send(document, 3); hr.Err != nil {
+		t.Log(hr.Err)
+		t.Fail()
+	} else if hr.ResponseBody != "Bearer token-1 /me" {
+		t.Log(hr.ResponseBody)
+		t.Fail()
+	}
+
+	// and its result is reused afterwards
+	if hr := c.Send(document, 4); hr.Err != nil || hr.ResponseBody != "Bearer token-1 /me" {
+		t.Fail()
+	}
+
+	// until it is sent again
+	if hr := c.Send(document, 1); hr.Err != nil {
+		t.Fail()
+	}
+	if hr := c.Send(document, 3); hr.Err != nil || hr.ResponseBody != "Bearer token-2 /me" {
+		t.Fail()
+	}
+
+	// a copy in another directory shares the results and leaves the client as it is
+	dir := t.TempDir()
+	copied := c.InDir(dir)
+	if hr := copied.Send(document, 3); hr.Err != nil || hr.ResponseBody != "Bearer token-2 /me" || copied.BaseDir != dir || c.BaseDir != "" {
+		t.Fail()
+	}
+	if hr := copied.Send(document, 1); hr.Err != nil {
+		t.Fail()
+	}
+	if hr := c.Send(document, 3); hr.Err != nil || hr.ResponseBody != "Bearer token-3 /me" {
+		t.Fail()
+	}
+
+	if hr := c.Send(document, 8); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func TestReferenceLoop(t *testing.T) {
+	document := `# @name a
+GET "localhost/{{b.response.body.*}}"
+###
+# @name b
+GET "localhost/{{a.response.body.*}}"`
+
+	if hr := (&Client{}).Send(document, 0); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func TestAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		name          string
+		input         string
+		authorization string
+	}{
+		{"Basic", `GET "%s" -auth alice:p:ss`, "Basic YWxpY2U6cDpzcw=="},
+		{"Bearer", `GET "%s" -bearer abc.def`, "Bearer abc.def"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Fail()
+			} else if hr.ResponseBody != test.authorization {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			}
+		})
+	}
+
+	for _, input := range []string{`GET "%s" -auth alice`, `GET "%s" -digest`, `GET "%s" -bearer`,
+		`GET "%s" -bearer abc -auth alice:pass`, `GET "%s" -bearer abc -auth alice:pass -digest`, `GET "%s" -bearer abc -digest`} {
+		if hr := Hit(fmt.Sprintf(input, server.URL)); hr.Err == nil {
+			t.Fail()
+		}
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="devices", qop="auth,auth-int", nonce="abc123", opaque="xyz"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := parseChallenge(authorization[7:])
+		ha1 := md5hex("alice:devices:secret")
+		ha2 := md5hex(r.Method + ":" + r.URL.RequestURI())
+		expected := md5hex(ha1 + ":abc123:" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["response"] != expected || params["opaque"] != "xyz" || params["uri"] != r.URL.RequestURI() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"on": true}`), 0644); err != nil {
+		panic(err)
+	}
+
+	var tests = []struct {
+		name     string
+		input    string
+		status   string
+		response string
+	}{
+		{"Without credentials", `GET "%s/status"`, "401 Unauthorized", ""},
+		{"Wrong password", `GET "%s/status?x=1" -auth alice:wrong -digest`, "403 Forbidden", ""},
+		{"Digest", `GET "%s/status?x=1" -auth alice:secret -digest`, "200 OK", ""},
+		{"Digest with items", `POST "%s/config" on:=true -auth alice:secret -digest`, "200 OK", `{"on":true}`},
+		{"Digest with body file", `POST "%s/config" < ` + file + ` -auth alice:secret -digest`, "200 OK", `{"on": true}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseHeaders[0] != test.status {
+				t.Log(hr.ResponseHeaders[0])
+				t.Fail()
+			} else if hr.ResponseBody != test.response {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	var grants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+			return
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "hitman" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		_ = r.ParseForm()
+		grants = append(grants, r.PostForm.Get("grant_type"))
+		expiresIn := 3600
+		if r.PostForm.Get("scope") == "short" {
+			// expires before it can be used again
+			expiresIn = 10
+		}
+		_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d, "refresh_token": "refresh"}`, len(grants), expiresIn)
+	}))
+	defer server.Close()
+
+	environments := fmt.Sprintf(`{
+	"long": {"secret": "s3cret", "oauth2": {"grantType": "client_credentials", "tokenUrl": "%[1]s/token", "clientId": "hitman", "clientSecret": "{{secret}}"}},
+	"short": {"oauth2": {"grantType": "client_credentials", "tokenUrl": "%[1]s/token", "clientId": "hitman", "clientSecret": "s3cret", "scope": "short"}},
+	"wrong": {"oauth2": {"grantType": "client_credentials", "tokenUrl": "%[1]s/token", "clientId": "hitman", "clientSecret": "wrong"}},
+	"body": {"oauth2": {"grantType": "password", "tokenUrl": "%[1]s/token", "clientId": "hitman", "clientSecret": "s3cret", "clientAuthInBody": true, "username": "alice", "password": "pa55"}},
+	"socket": {"oauth2": {"grantType": "client_credentials", "tokenUrl": "%[1]s/token", "clientId": "hitman", "clientSecret": "s3cret"}}
+}`, server.URL)
+	writeEnvironments(environments)
+
+	var tests = []struct {
+		name          string
+		input         string
+		authorization string
+		grants        []string
+	}{
+		{"Token is fetched", `GET "%s/api" -env long`, "Bearer token-1", []string{"client_credentials"}},
+		{"Token is cached", `GET "%s/api" -env long`, "Bearer token-1", []string{"client_credentials"}},
+		{"Explicit header wins", `GET "%s/api" Authorization: mine -env long`, "mine", []string{"client_credentials"}},
+		{"OAuth can be skipped", `GET "%s/api" -env long -no-oauth`, "", []string{"client_credentials"}},
+		{"Short-lived token is fetched", `GET "%s/api" -env short`, "Bearer token-2", []string{"client_credentials", "client_credentials"}},
+		{"Expired token is refreshed", `GET "%s/api" -env short`, "Bearer token-3", []string{"client_credentials", "client_credentials", "refresh_token"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != test.authorization {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			} else if strings.Join(grants, ",") != strings.Join(test.grants, ",") {
+				t.Log(grants)
+				t.Fail()
+			} else if strings.Contains(strings.Join(hr.RequestHeaders, "\n"), "s3cret") {
+				// the client secret is not recorded in notes
+				t.Log(hr.RequestHeaders)
+				t.Fail()
+			}
+		})
+	}
+
+	hr := Hit(fmt.Sprintf(`GET "%s/api" -env wrong`, server.URL))
+	if hr.Err == nil || hr.TokenExchange == nil || hr.TokenExchange.ResponseHeaders[0] != "401 Unauthorized" {
+		t.Fail()
+	}
+
+	// the token is not requested through the transport of the request
+	hr = Hit(fmt.Sprintf(`GET "%s/api" -env socket -unix-socket %s`, server.URL, filepath.Join(home, "missing.sock")))
+	if hr.Err == nil || hr.TokenExchange == nil || hr.TokenExchange.Err != nil || hr.TokenExchange.ResponseHeaders[0] != "200 OK" {
+		t.Log(hr.Err, hr.TokenExchange)
+		t.Fail()
+	}
+
+	// credentials sent in the form are hidden like the Authorization header
+	hr = Hit(fmt.Sprintf(`GET "%s/api" -env body`, server.URL))
+	if hr.TokenExchange == nil {
+		t.FailNow()
+	}
+	if !contains(hr.TokenExchange.RequestHeaders, "# form client_secret : s3cret") {
+		t.Log(hr.TokenExchange.RequestHeaders)
+		t.Fail()
+	}
+	redacted := RedactResult(hr.TokenExchange.RequestHeaders, nil)
+	if exchange := strings.Join(redacted, "\n"); strings.Contains(exchange, "s3cret") || strings.Contains(exchange, "pa55") || !contains(redacted, "# form username : alice") {
+		t.Log(redacted)
+		t.Fail()
+	}
+}
+
+func TestSigV4(t *testing.T) {
+	// from the AWS Signature Version 4 test suite
+	now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	creds := awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	var tests = []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+			if _, err := signSigV4(req, "us-east-1", "service", creds); err != nil {
+				t.Log(err)
+				t.Fail()
+			} else if expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + test.signature; req.Header.Get("Authorization") != expected {
+				t.Log(req.Header.Get("Authorization"))
+				t.Fail()
+			}
+		})
+	}
+
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "work")
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0755); err != nil {
+		panic(err)
+	}
+	credentials := "[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = default\n\n[work]\naws_access_key_id = AKIDWORK\naws_secret_access_key = work\naws_session_token = session\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(credentials), 0600); err != nil {
+		panic(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization") + "\n" + r.Header.Get("X-Amz-Security-Token")))
+	}))
+	defer server.Close()
+
+	hr := Hit(fmt.Sprintf(`POST "%s/items" name=hitman -aws-sigv4 eu-west-1/execute-api`, server.URL))
+	if hr.Err != nil {
+		t.Log(hr.Err)
+		t.Fail()
+	} else if !strings.HasPrefix(hr.ResponseBody, "AWS4-HMAC-SHA256 Credential=AKIDWORK/20150830/eu-west-1/execute-api/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, ") || !strings.HasSuffix(hr.ResponseBody, "\nsession") {
+		t.Log(hr.ResponseBody)
+		t.Fail()
+	} else if !contains(hr.RequestHeaders, "# AWS SigV4 string to sign") || !contains(hr.RequestHeaders, "#   POST") {
+		t.Log(hr.RequestHeaders)
+		t.Fail()
+	}
+
+	if hr := Hit(fmt.Sprintf(`GET "%s" -aws-sigv4 eu-west-1`, server.URL)); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("WEBHOOK_SECRET", "whsec")
+
+	environments := `{
+	"stripe": {"hmac": {"secret": "{{$env WEBHOOK_SECRET}}", "parts": ["timestamp", "body"], "header": "Stripe-Signature", "format": "t={timestamp},v1={signature}"}},
+	"slack": {"hmac": {"algorithm": "sha256", "secret": "whsec", "parts": ["literal:v0", "timestamp", "body"], "separator": ":", "header": "X-Slack-Signature", "format": "v0={signature}", "timestampHeader": "X-Slack-Request-Timestamp"}},
+	"broken": {"hmac": {"secret": "whsec", "parts": ["query"], "header": "X-Signature"}},
+	"plain": {}
+}`
+	writeEnvironments(environments)
+
+	sign := func(message string) string {
+		mac := hmac.New(sha256.New, []byte("whsec"))
+		_, _ = io.WriteString(mac, message)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if header := r.Header.Get("Stripe-Signature"); header != "" {
+			var timestamp, signature string
+			_, _ = fmt.Sscanf(strings.Replace(header, ",", " ", 1), "t=%s v1=%s", &timestamp, &signature)
+			_, _ = fmt.Fprint(w, signature == sign(timestamp+"."+string(body)))
+		} else if header := r.Header.Get("X-Slack-Signature"); header != "" {
+			_, _ = fmt.Fprint(w, header == "v0="+sign("v0:"+r.Header.Get("X-Slack-Request-Timestamp")+":"+string(body)))
+		}
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{"Stripe style", `POST "%s" event=charge.succeeded -env stripe -hmac`, true},
+		{"Slack style", `POST "%s" type=event_callback -env slack -hmac`, true},
+		{"Unknown part", `POST "%s" a=b -env broken -hmac`, false},
+		{"No settings", `POST "%s" a=b -env plain -hmac`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hr := Hit(fmt.Sprintf(test.input, server.URL))
+			if !test.valid {
+				if hr.Err == nil {
+					t.Fail()
+				}
+				return
+			}
+			if hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != "true" {
+				t.Log(hr.RequestHeaders)
+				t.Fail()
+			} else if strings.Contains(strings.Join(hr.RequestHeaders, "\n"), "whsec") {
+				// the secret is not recorded in notes
+				t.Log(hr.RequestHeaders)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv(store.PassphraseEnv, "")
+	if err := store.SaveSecrets(map[string]string{"token": "s3cret"}); err != nil {
+		panic(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	hr := Hit(fmt.Sprintf(`GET "%s" Authorization: "Bearer {{$secret token}}"`, server.URL))
+	if hr.Err != nil {
+		t.Log(hr.Err)
+		t.Fail()
+	} else if hr.ResponseBody != "Bearer s3cret" {
+		t.Log(hr.ResponseBody)
+		t.Fail()
+	} else if !contains(hr.RequestHeaders, "# {{$secret token}} = "+Redacted) {
+		t.Log(hr.RequestHeaders)
+		t.Fail()
+	} else if redacted := RedactResult(hr.RequestHeaders, nil); strings.Contains(strings.Join(redacted, "\n"), "s3cret") {
+		t.Log(redacted)
+		t.Fail()
+	}
+
+	if hr := Hit(fmt.Sprintf(`GET "%s" Authorization: "Bearer {{$secret missing}}"`, server.URL)); hr.Err == nil {
+		t.Fail()
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var results = []struct {
+		line     string
+		expected string
+	}{
+		{"Authorization : Bearer abc", "Authorization : " + Redacted},
+		{"Cookie : a=b; c=d", "Cookie : " + Redacted},
+		{"Set-Cookie : a=b; Path=/", "Set-Cookie : " + Redacted},
+		{"X-Api-Key : abc", "X-Api-Key : " + Redacted},
+		{"Accept : */*", "Accept : */*"},
+		{"# Answered Digest challenge with Authorization : Digest username=\"a\"", "# Answered Digest challenge with Authorization : " + Redacted},
+		{"# {{login.response.body.$.token}} in Authorization : abc", "# {{login.response.body.$.token}} in Authorization : " + Redacted},
+		{"# {{key}} in X-Api-Key : abc", "# {{key}} in X-Api-Key : " + Redacted},
+		{"# {{token}} in -bearer : abc", "# {{token}} in -bearer : " + Redacted},
+		{"# {{id}} in X-Request-Id : 42", "# {{id}} in X-Request-Id : 42"},
+		{"# {{id}} = 42", "# {{id}} = 42"},
+		{"# form refresh_token : abc", "# form refresh_token : " + Redacted},
+		{"# form grant_type : password", "# form grant_type : password"},
+	}
+	for _, test := range results {
+		if redacted := RedactResult([]string{test.line}, []string{"x-api-key"}); redacted[0] != test.expected {
+			t.Log(redacted[0])
+			t.Fail()
+		}
+	}
+
+	setHome(t, t.TempDir())
+	t.Setenv(store.PassphraseEnv, "")
+	text := `GET example.com
+Authorization: "Bearer abc" X-Request-Id: 1
+x-api-key:abc # same key
+Cookie: {{cookie}}
+Accept: */*
+-auth user:pass -bearer "{{token}}" -insecure
+###
+GET localhost:8080/auth`
+	expected := `GET example.com
+Authorization: {{$secret input-1}} X-Request-Id: 1
+x-api-key:{{$secret input-2}} # same key
+Cookie: {{cookie}}
+Accept: */*
+-auth {{$secret input-3}} -bearer "{{token}}" -insecure
+###
+GET localhost:8080/auth`
+	for i := 0; i < 2; i++ {
+		if stored, err := StoreCredentials(text, []string{"X-Api-Key"}); err != nil || stored != expected {
+			t.Log(stored, err)
+			t.Fail()
+		}
+	}
+	secrets, err := store.LoadSecrets()
+	if err != nil || len(secrets) != 3 || secrets["input-1"] != "Bearer abc" || secrets["input-2"] != "abc" || secrets["input-3"] != "user:pass" {
+		t.Log(secrets, err)
+		t.Fail()
+	}
+	if stored, err := StoreCredentials("GET example.com\nAuthorization: Bearer xyz", nil); err != nil || stored != "GET example.com\nAuthorization: {{$secret input-4}}" {
+		t.Log(stored, err)
+		t.Fail()
+	}
+}
+
+func TestProjectEnvironments(t *testing.T) {
+	setHome(t, t.TempDir())
+	writeEnvironments(`{"dev": {"host": "global.example.com", "token": "global"}, "prod": {"host": "example.com"}}`)
+
+	project := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(project, ".hitman"), 0755); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".hitman", "environments.json"), []byte(`{"dev": {"host": "dev.example.com"}}`), 0644); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "http-client.private.env.json"), []byte(`{"dev": {"token": "private"}}`), 0644); err != nil {
+		panic(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Query().Get("host") + " " + r.URL.Query().Get("token")))
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		name     string
+		client   *Client
+		input    string
+		expected string
+	}{
+		{"Global environment", &Client{}, `GET "%s" host=={{host}} token=={{token}} -env dev`, "global.example.com global"},
+		{"Project environment", &Client{ProjectDir: project}, `GET "%s" host=={{host}} token=={{token}} -env dev`, "dev.example.com private"},
+		{"Environment only defined globally", &Client{ProjectDir: project}, `GET "%s" host=={{host}} -env prod`, "example.com "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hr := test.client.Hit(fmt.Sprintf(test.input, server.URL)); hr.Err != nil {
+				t.Log(hr.Err)
+				t.Fail()
+			} else if hr.ResponseBody != test.expected {
+				t.Log(hr.ResponseBody)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestHTTPFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), body)
+	}))
+	defer server.Close()
+
+	document := fmt.Sprintf(`@base = %s
+@api = {{base}}/api
+
+### create a user
+POST {{api}}/users?page=1 HTTP/1.1
+Content-Type: application/json
+
+{"name": "{{name}}"}
+
+> {%% client.global.set("id", response.body.id); %%}
+
+###
+@name = hitman
+GET {{api}}/users
+`, server.URL)
+
+	c := &Client{}
+	hr := c.Send(document, 5)
+	if hr.Err != nil {
+		t.Log(hr.Err)
+		t.FailNow()
+	}
+	if hr.ResponseBody != `POST /api/users?page=1 application/json {"name": "hitman"}` {
+		t.Log(hr.ResponseBody)
+		t.Fail()
+	}
+
+	var warned bool
+	for _, line := range hr.RequestHeaders {
+		warned = warned || line == "# Warning: response handler ignored"
+	}
+	if !warned {
+		t.Log(hr.RequestHeaders)
+		t.Fail()
+	}
+}
+
+func TestDiff(t *testing.T) {
+	setHome(t, t.TempDir())
+	writeEnvironments(`{"staging": {}}`)
+	var deployed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+		switch {
+		case r.URL.Path == "/text" && deployed:
+			_, _ = w.Write([]byte("one\nthree\nfour"))
+		case r.URL.Path == "/text":
+			_, _ = w.Write([]byte("one\ntwo\nthree"))
+		case deployed:
+			w.Header().Set("X-Version", "2")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 1, "tags": ["a"], "owner": {"name": "bob"}, "my key": true}`))
+		default:
+			w.Header().Set("X-Version", "1")
+			w.Header().Set("X-Legacy", "yes")
+			_, _ = w.Write([]byte(`{"id": 1, "tags": ["a", "b"], "owner": {"name": "alice"}}`))
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{}
+	baseline := c.Hit(fmt.Sprintf(`GET "%s/users" q==1 -no-cookies`, server.URL))
+	textBaseline := c.Hit(fmt.Sprintf(`GET "%s/text" -no-cookies`, server.URL))
+	deployed = true
+	result := c.Hit(fmt.Sprintf(`GET "%s/users" q==1 -no-cookies`, server.URL))
+	textResult := c.Hit(fmt.Sprintf(`GET "%s/text" -no-cookies`, server.URL))
+	if baseline.Err != nil || result.Err != nil || textBaseline.Err != nil || textResult.Err != nil {
+		t.FailNow()
+	}
+
+	// another query or environment makes it another request
+	if baseline.BaselineKey() != result.BaselineKey() || baseline.BaselineKey() != "GET "+server.URL+"/users?q=1" {
+		t.Log(baseline.BaselineKey(), result.BaselineKey())
+		t.Fail()
+	}
+	other := c.Hit(fmt.Sprintf(`GET "%s/users" q==2 -no-cookies`, server.URL))
+	staging := c.Hit(fmt.Sprintf(`GET "%s/users" q==1 -no-cookies -env staging`, server.URL))
+	if other.BaselineKey() != "GET "+server.URL+"/users?q=2" || staging.BaselineKey() != "GET "+server.URL+"/users?q=1 in staging" {
+		t.Log(other.BaselineKey(), staging.BaselineKey(), staging.Err)
+		t.Fail()
+	}
+
+	format := func(diff []DiffLine) string {
+		var lines []string
+		for _, line := range diff {
+			lines = append(lines, fmt.Sprintf("%s%s", []string{"= ", "  ", "- ", "+ "}[line.Kind], line.Text))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	expected := `= Status
+- 200 OK
++ 201 Created
+= Headers
++ Cache-Control : no-store
+- Content-Length : 57
++ Content-Length : 66
+- X-Legacy : yes
+- X-Version : 1
++ X-Version : 2
+  2 unchanged
+= Body
++ $["my key"]: true
+- $.owner.name: "alice"
++ $.owner.name: "bob"
+- $.tags[1]: "b"`
+	if diff := format(DiffResults(baseline, result)); diff != expected {
+		t.Log(diff)
+		t.Fail()
+	}
+
+	expected = `= Status
+  200 OK
+= Headers
+- Content-Length : 13
++ Content-Length : 14
+  2 unchanged
+= Body
+  one
+- two
+  three
++ four`
+	if diff := format(DiffResults(textBaseline, textResult)); diff != expected {
+		t.Log(diff)
+		t.Fail()
+	}
+}
+
+func TestSendInEnvironments(t *testing.T) {
+	setHome(t, t.TempDir())
+
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				_, _ = w.Write([]byte(`{"token": "` + name + `-token"}`))
+				return
+			}
+			w.Header().Set("X-Server", name)
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+	}
+	staging, production := newServer("staging"), newServer("production")
+	defer staging.Close()
+	defer production.Close()
+
+	writeEnvironments(fmt.Sprintf(`{"staging": {"host": "%s"}, "production": {"host": "%s"}, "local": {"host": "localhost:1"}}`, staging.URL, production.URL))
+
+	document := `# @name login
+POST "{{host}}/login" -no-cookies
+###
+GET "{{host}}/me"
+Authorization: "Bearer {{login.response.body.$.token}}"
+-env local -no-cookies`
+
+	c := &Client{}
+	results := c.SendInEnvironments(document, 3, "staging", "production")
+	if len(results) != 2 {
+		t.FailNow()
+	}
+	for i, name := range []string{"staging", "production"} {
+		// -env is replaced and references are resolved in each environment
+		if results[i].Err != nil {
+			t.Log(results[i].Err)
+			t.Fail()
+		} else if results[i].ResponseBody != "Bearer "+name+"-token" || results[i].header.Get("X-Server") != name {
+			t.Log(results[i].ResponseBody)
+			t.Fail()
+		}
+	}
+
+	if results := c.SendInEnvironments(document, 3, "staging", "missing"); results[0].Err != nil || results[1].Err == nil {
+		t.Fail()
+	}
+
+	if names, err := c.Environments(); err != nil || strings.Join(names, " ") != "local production staging" {
+		t.Log(names, err)
+		t.Fail()
+	}
+
+	// cookies set in both environments end up in the same session
+	cookieServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: name, Value: "sid"})
+			_, _ = w.Write([]byte(r.Header.Get("Cookie")))
+		}))
+	}
+	for round := 0; round < 5; round++ {
+		staging, production := cookieServer(fmt.Sprintf("staging%d", round)), cookieServer(fmt.Sprintf("production%d", round))
+		writeEnvironments(fmt.Sprintf(`{"staging": {"host": "%s"}, "production": {"host": "%s"}}`, staging.URL, production.URL))
+		results := c.SendInEnvironments(`GET "{{host}}/"`, 0, "staging", "production")
+		staging.Close()
+		production.Close()
+		if results[0].Err != nil || results[1].Err != nil {
+			t.Log(results[0].Err, results[1].Err)
+			t.FailNow()
+		}
+	}
+	session, err := store.LoadSession(store.DefaultSession)
+	if err != nil {
+		panic(err)
+	}
+	if cookies := session.List(); len(cookies) != 10 {
+		t.Log(cookies)
+		t.Fail()
+	}
+}
@@ -0,0 +1,200 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+)
+
+var flagMultipart = "multipart"
+
+// A file part of a multipart body written as name < ./path;filename=name.ext;type=mime/type
+type filePart struct {
+	name        string
+	path        string
+	filename    string
+	contentType string
+	size        int64
+}
+
+// Parse the value of a file part and check that the file can be read
+func parseFilePart(item parser.Item) (*filePart, error) {
+	attrs := strings.Split(item.Value, ";")
+	part := &filePart{
+		name: item.Name,
+		path: strings.TrimSpace(attrs[0]),
+	}
+
+	for _, attr := range attrs[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		switch k {
+		case "filename":
+			part.filename = v
+		case "type":
+			part.contentType = v
+		default:
+			return nil, fmt.Errorf("%s: unknown file part attribute %s", item.Name, k)
+		}
+	}
+
+	info, err := os.Stat(part.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s for %s: %w", part.path, item.Name, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s for %s is a directory", part.path, item.Name)
+	}
+	part.size = info.Size()
+
+	if part.filename == "" {
+		part.filename = filepath.Base(part.path)
+	}
+	if part.contentType == "" {
+		part.contentType = mime.TypeByExtension(filepath.Ext(part.path))
+	}
+	if part.contentType == "" {
+		part.contentType = "application/octet-stream"
+	}
+	return part, nil
+}
+
+// Reports whether a request item is sent as a file part of a multipart body
+func isFilePart(item parser.Item) bool {
+	return item.Op == "<" || item.Op == "@"
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (p *filePart) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(p.name), quoteEscaper.Replace(p.filename)))
+	h.Set("Content-Type", p.contentType)
+	return h
+}
+
+// A field or file of a multipart body
+type multipartItem struct {
+	name  string
+	value string
+	file  *filePart
+}
+
+// Write the multipart body to w
+// When withFiles is false, files are skipped so that the rest of the body can be measured
+func writeMultipart(w *multipart.Writer, items []multipartItem, withFiles bool) error {
+	for _, item := range items {
+		if item.file == nil {
+			if err := w.WriteField(item.name, item.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		part, err := w.CreatePart(item.file.header())
+		if err != nil {
+			return err
+		}
+		if !withFiles {
+			continue
+		}
+
+		f, err := os.Open(item.file.path)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(part, f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+		if n != item.file.size {
+			return fmt.Errorf("%s changed while it was being sent", item.file.path)
+		}
+	}
+	return w.Close()
+}
+
+// Counts bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// Build a multipart/form-data body that streams files from disk
+func encodeMultipart(items []parser.Item) (*requestBody, error) {
+	var parts []multipartItem
+	var summary []string
+
+	for _, item := range items {
+		if isFilePart(item) {
+			file, err := parseFilePart(item)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, multipartItem{name: item.Name, file: file})
+			summary = append(summary, fmt.Sprintf("%s < %s (filename=%s; type=%s; %d bytes)",
+				item.Name, file.path, file.filename, file.contentType, file.size))
+			continue
+		}
+
+		if strings.HasPrefix(item.Op, ":=") {
+			return nil, fmt.Errorf("%s: raw JSON fields cannot be sent in a multipart body", item.Name)
+		}
+		value, err := itemValue(item)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, multipartItem{name: item.Name, value: value})
+		summary = append(summary, item.Name+"="+value)
+	}
+
+	// measure everything but the files to send a Content-Length instead of a chunked body
+	counter := &countingWriter{}
+	measuring := multipart.NewWriter(counter)
+	if err := writeMultipart(measuring, parts, false); err != nil {
+		return nil, err
+	}
+	length := counter.n
+	for _, part := range parts {
+		if part.file != nil {
+			length += part.file.size
+		}
+	}
+
+	// files are streamed through a pipe; every call starts writing the body from the beginning
+	open := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(measuring.Boundary()); err != nil {
+			return nil, err
+		}
+		go func() {
+			pw.CloseWithError(writeMultipart(writer, parts, true))
+		}()
+		return pr, nil
+	}
+
+	reader, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &requestBody{
+		reader:      reader,
+		reopen:      open,
+		length:      length,
+		contentType: measuring.FormDataContentType(),
+		summary:     summary,
+	}, nil
+}
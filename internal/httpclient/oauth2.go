@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ramitmittal/hitman/internal/store"
+	"github.com/ramitmittal/hitman/internal/template"
+)
+
+var flagNoOAuth = "no-oauth"
+
+// Token endpoints are not reached through the transport of the request, which may
+// dial a socket, pin addresses or skip TLS verification for the request's own host
+var tokenTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+// Returns the config with {{expressions}} in its values replaced
+func expandOAuth2Config(config store.OAuth2Config, resolve template.Resolver) (store.OAuth2Config, error) {
+	var err error
+	for _, value := range []*string{
+		&config.GrantType, &config.TokenURL, &config.ClientID, &config.ClientSecret,
+		&config.Scope, &config.Username, &config.Password, &config.RefreshToken,
+	} {
+		if *value, err = template.Expand(*value, resolve); err != nil {
+			return config, fmt.Errorf("oauth2: %w", err)
+		}
+	}
+	return config, nil
+}
+
+// Returns the key that a token is cached under
+// Changing the settings that a token was fetched with invalidates it
+func tokenCacheKey(env string, config store.OAuth2Config) string {
+	return strings.Join([]string{env, config.GrantType, config.TokenURL, config.ClientID, config.Username, config.Scope}, "|")
+}
+
+// The fields of a token endpoint's response that hitman uses
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// Request a token from the token endpoint
+// The exchange is returned for display even when it fails
+func requestToken(config store.OAuth2Config, grantType string, refreshToken string) (store.Token, *HitResult, error) {
+	exchange := &HitResult{}
+
+	form := url.Values{}
+	form.Set("grant_type", grantType)
+	if config.Scope != "" {
+		form.Set("scope", config.Scope)
+	}
+	switch grantType {
+	case "client_credentials":
+	case "password":
+		form.Set("username", config.Username)
+		form.Set("password", config.Password)
+	case "refresh_token":
+		form.Set("refresh_token", refreshToken)
+	default:
+		return store.Token{}, nil, errors.New("oauth2: unsupported grant type " + grantType)
+	}
+	if config.ClientAuthInBody {
+		form.Set("client_id", config.ClientID)
+		if config.ClientSecret != "" {
+			form.Set("client_secret", config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return store.Token{}, nil, fmt.Errorf("oauth2: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !config.ClientAuthInBody && config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+	exchange.RequestHeaders = append(formatRequest(req, nil), formatForm(form)...)
+
+	res, err := (&http.Client{Transport: tokenTransport}).Do(req)
+	if err != nil {
+		exchange.Err = err
+		return store.Token{}, exchange, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	exchange.ResponseHeaders = formatResponseHeaders(res)
+	exchange.ResponseBody = formatResponseBody(body)
+	if err != nil {
+		exchange.Err = err
+		return store.Token{}, exchange, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+
+	var parsed tokenResponse
+	if err := json.Unmarshal(body, &parsed); err != nil || res.StatusCode != http.StatusOK || parsed.AccessToken == "" {
+		msg := res.Status
+		if parsed.Error != "" {
+			msg = parsed.Error + " " + parsed.Description
+		}
+		exchange.Err = errors.New(msg)
+		return store.Token{}, exchange, errors.New("oauth2 token request failed: " + msg)
+	}
+
+	token := store.Token{
+		AccessToken:  parsed.AccessToken,
+		TokenType:    parsed.TokenType,
+		RefreshToken: parsed.RefreshToken,
+	}
+	if parsed.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken == "" && grantType == "refresh_token" {
+		// the server may keep the refresh token unchanged
+		token.RefreshToken = refreshToken
+	}
+	return token, exchange, nil
+}
+
+// Format the fields of a token request as lines of the request section, like "# form grant_type : password",
+// so that credentials among them are hidden like sensitive headers
+func formatForm(form url.Values) []string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		for _, value := range form[name] {
+			lines = append(lines, "# form "+name+" : "+value)
+		}
+	}
+	return lines
+}
+
+// Returns an access token for the environment from the cache, or from the token endpoint when none is cached or it expired
+// Expired tokens are refreshed when the server gave a refresh token
+func oauth2Token(env string, config store.OAuth2Config) (token store.Token, exchange *HitResult, err error) {
+	if config.TokenURL == "" {
+		return store.Token{}, nil, errors.New("oauth2: tokenUrl is required")
+	}
+
+	key := tokenCacheKey(env, config)
+	cached, ok, err := store.LoadToken(key)
+	if err != nil {
+		return store.Token{}, nil, err
+	}
+	if ok && cached.Valid() {
+		return cached, nil, nil
+	}
+
+	refreshed := false
+	if ok && cached.RefreshToken != "" {
+		token, exchange, err = requestToken(config, "refresh_token", cached.RefreshToken)
+		refreshed = err == nil
+	}
+	if !refreshed {
+		token, exchange, err = requestToken(config, config.GrantType, config.RefreshToken)
+	}
+	if err != nil {
+		return store.Token{}, exchange, err
+	}
+
+	if err := store.SaveToken(key, token); err != nil {
+		return store.Token{}, exchange, fmt.Errorf("could not cache oauth2 token: %w", err)
+	}
+	return token, exchange, nil
+}
+
+// Format a token as the value of an Authorization header
+func authorizationValue(token store.Token) string {
+	if token.TokenType == "" || strings.EqualFold(token.TokenType, "bearer") {
+		return "Bearer " + token.AccessToken
+	}
+	return token.TokenType + " " + token.AccessToken
+}
+
+// Set the Authorization header from the OAuth2 settings of the request's environment
+// Requests that set their own Authorization header or use -no-oauth are left alone
+// Returns a note for the request section
+func (c *Client) authorizeOAuth2(req *http.Request, flags map[string]string, resolve template.Resolver, hr *HitResult) (string, error) {
+	if _, prs := flags[flagNoOAuth]; prs || req.Header.Get("Authorization") != "" {
+		return "", nil
+	}
+	for _, other := range []string{flagDigest, flagAWSSigV4} {
+		if _, prs := flags[other]; prs {
+			return "", nil
+		}
+	}
+
+	env, err := c.environment(flags)
+	if err != nil || env.OAuth2 == nil {
+		return "", err
+	}
+	config, err := expandOAuth2Config(*env.OAuth2, resolve)
+	if err != nil {
+		return "", err
+	}
+
+	token, exchange, err := oauth2Token(c.environmentName(flags), config)
+	hr.TokenExchange = exchange
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", authorizationValue(token))
+
+	if exchange == nil {
+		return "# Authorization from cached OAuth2 token", nil
+	}
+	return "# Authorization from OAuth2 token endpoint " + config.TokenURL, nil
+}
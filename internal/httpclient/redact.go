@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/store"
+)
+
+// Replaces hidden values
+const Redacted = "********"
+
+// Headers that are always hidden
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Flags whose values are credentials
+var sensitiveFlags = []string{flagAuth, flagBearer}
+
+// Fields of OAuth2 token requests that are credentials
+var sensitiveFormFields = []string{"password", "client_secret", "refresh_token"}
+
+// Reports whether a header is always hidden or is one of the extra names
+func isSensitiveHeader(name string, extra []string) bool {
+	for _, list := range [][]string{sensitiveHeaders, extra} {
+		for _, h := range list {
+			if strings.EqualFold(h, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reports whether a flag, like "-bearer", takes a credential
+func isSensitiveFlag(flag string) bool {
+	for _, f := range sensitiveFlags {
+		if flag == "-"+f {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a field of a token request is a credential
+func isSensitiveFormField(field string) bool {
+	for _, f := range sensitiveFormFields {
+		if field == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Hide the values of sensitive headers in lines of a result like "Authorization : Bearer abc"
+// Notes that end with a header or credential flag, like "# Answered Digest challenge with Authorization : Digest ..."
+// or "# {{token}} in -bearer : abc", and credentials of token requests, like "# form client_secret : abc", are hidden too
+func RedactResult(lines []string, extra []string) []string {
+	redacted := make([]string, len(lines))
+	for i, line := range lines {
+		redacted[i] = line
+		name, _, found := strings.Cut(line, " : ")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(name)
+		if len(fields) == 0 {
+			continue
+		}
+		last := fields[len(fields)-1]
+		if isSensitiveHeader(last, extra) || isSensitiveFlag(last) || isSensitiveFormField(last) && strings.HasPrefix(line, "# form ") {
+			redacted[i] = name + " : " + Redacted
+		}
+	}
+	return redacted
+}
+
+// Prefix of the names of secrets that hold credentials moved out of the input
+const inputSecretPrefix = "input-"
+
+var (
+	// a header line; the value ends at another header, a flag or a comment on the same line
+	headerValue = regexp.MustCompile(`^(\s*([A-Za-z0-9!#$%&'*+.^_|~-]+)\s*:\s*)("[^"]*"|.*?)(\s+[A-Za-z0-9!#$%&'*+.^_|~-]+\s*:.*|\s+-[A-Za-z].*|\s+#.*|\s*)$`)
+
+	// a credential flag and its value
+	flagValue = regexp.MustCompile(`(^|\s)-(` + strings.Join(sensitiveFlags, "|") + `)(\s+)("[^"]*"|[^\s"]+)`)
+)
+
+// Move literal values of sensitive headers and credential flags in request text into the secrets store,
+// e.g. before the input is saved, and return the text with {{$secret input-N}} in their place
+// Values that use {{expressions}} are kept, as they do not contain the secret itself
+// A value that is already stored keeps its name, so saving the same text twice gives the same result
+func StoreCredentials(text string, extra []string) (string, error) {
+	var secrets map[string]string
+	names := map[string]string{}
+	added := false
+	var err error
+
+	// Returns a reference to the secret that holds a value, or nothing when the value is kept
+	reference := func(value string) string {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+		if value == "" || strings.Contains(value, "{{") || err != nil {
+			return ""
+		}
+		if secrets == nil {
+			if secrets, err = store.LoadSecrets(); err != nil {
+				return ""
+			}
+			for name, v := range secrets {
+				if strings.HasPrefix(name, inputSecretPrefix) {
+					names[v] = name
+				}
+			}
+		}
+
+		name, prs := names[value]
+		if !prs {
+			for n := 1; ; n++ {
+				name = inputSecretPrefix + strconv.Itoa(n)
+				if _, taken := secrets[name]; !taken {
+					break
+				}
+			}
+			secrets[name], names[value], added = value, name, true
+		}
+		return "{{$secret " + name + "}}"
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if m := headerValue.FindStringSubmatch(line); m != nil && isSensitiveHeader(m[2], extra) {
+			if ref := reference(m[3]); ref != "" {
+				lines[i] = m[1] + ref + m[4]
+			}
+			continue
+		}
+		lines[i] = flagValue.ReplaceAllStringFunc(line, func(match string) string {
+			m := flagValue.FindStringSubmatch(match)
+			if ref := reference(m[4]); ref != "" {
+				return m[1] + "-" + m[2] + m[3] + ref
+			}
+			return match
+		})
+	}
+	if err != nil {
+		return text, err
+	}
+	if added {
+		if err := store.SaveSecrets(secrets); err != nil {
+			return text, err
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
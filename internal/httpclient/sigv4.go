@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var flagAWSSigV4 = "aws-sigv4"
+
+// returns the time that requests are signed at; replaced in tests
+var now = time.Now
+
+// Credentials that AWS requests are signed with
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// Returns credentials from the AWS_* environment variables, or from the shared credentials file
+// The profile is picked by AWS_PROFILE and is "default" when it is not set
+func loadAWSCredentials() (awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{
+			accessKeyID:     id,
+			secretAccessKey: secret,
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	file := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, errors.New("no AWS credentials: " + err.Error())
+		}
+		file = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return awsCredentials{}, errors.New("no AWS credentials in the environment or " + file)
+	} else if err != nil {
+		return awsCredentials{}, err
+	}
+	defer f.Close()
+
+	values, err := readINISection(f, profile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("could not read %s: %w", file, err)
+	}
+	creds := awsCredentials{
+		accessKeyID:     values["aws_access_key_id"],
+		secretAccessKey: values["aws_secret_access_key"],
+		sessionToken:    values["aws_session_token"],
+	}
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials for profile %s in %s", profile, file)
+	}
+	return creds, nil
+}
+
+// Returns the key = value pairs of one [section] of an INI file
+func readINISection(r io.Reader, section string) (map[string]string, error) {
+	values := map[string]string{}
+
+	var current string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if k, v, found := strings.Cut(line, "="); found && current == section {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// Parse the value of -aws-sigv4
+func parseSigV4Scope(value string) (region, service string, err error) {
+	region, service, found := strings.Cut(value, "/")
+	if !found || region == "" || service == "" || strings.Contains(service, "/") {
+		return "", "", errors.New("-aws-sigv4 expects region/service")
+	}
+	return region, service, nil
+}
+
+// Percent-encode everything but unreserved characters, as AWS expects
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Returns the path part of the canonical request
+// Services other than S3 expect every path segment to be encoded twice
+func canonicalURI(u *url.URL, service string) string {
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	escaped := awsEscape(p, true)
+	if service != "s3" {
+		escaped = awsEscape(escaped, true)
+	}
+	return escaped
+}
+
+// Returns the query part of the canonical request
+func canonicalQuery(u *url.URL) string {
+	var pairs []string
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// Returns the canonical headers and the list of signed header names
+// Every header set on the request is signed
+func canonicalHeaders(req *http.Request) (canonical, signed string) {
+	values := map[string][]string{}
+	for k, vs := range req.Header {
+		name := strings.ToLower(k)
+		for _, v := range vs {
+			values[name] = append(values[name], strings.Join(strings.Fields(v), " "))
+		}
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values["host"] = []string{host}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.Join(values[name], ",") + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// Returns the hex encoded SHA-256 of the request body
+// Bodies that cannot be read twice are sent unsigned
+func payloadHash(req *http.Request) (string, error) {
+	h := sha256.New()
+	if req.Body == nil || req.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	if req.GetBody == nil {
+		return "UNSIGNED-PAYLOAD", nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(mac, data)
+	return mac.Sum(nil)
+}
+
+// Sign the request with AWS Signature Version 4
+// Returns notes with the canonical request and string to sign for the request section
+func signSigV4(req *http.Request, region, service string, creds awsCredentials) ([]string, error) {
+	hash, err := payloadHash(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", hash)
+	}
+
+	headers, signedHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL, service),
+		canonicalQuery(req.URL),
+		headers,
+		signedHeaders,
+		hash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+
+	notes := []string{"# AWS SigV4 canonical request"}
+	for _, line := range strings.Split(canonicalRequest, "\n") {
+		notes = append(notes, "#   "+line)
+	}
+	notes = append(notes, "# AWS SigV4 string to sign")
+	for _, line := range strings.Split(stringToSign, "\n") {
+		notes = append(notes, "#   "+line)
+	}
+	return notes, nil
+}
+
+// Sign the request when -aws-sigv4 is set
+// Must run after every header of the request is set
+func awsSign(req *http.Request, flags map[string]string) ([]string, error) {
+	value, prs := flags[flagAWSSigV4]
+	if !prs {
+		return nil, nil
+	}
+	region, service, err := parseSigV4Scope(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range []string{flagAuth, flagBearer} {
+		if _, prs := flags[conflict]; prs {
+			return nil, fmt.Errorf("-aws-sigv4 cannot be used with -%s", conflict)
+		}
+	}
+
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return signSigV4(req, region, service, creds)
+}
@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	flagResolve    = "resolve"
+	flagIPv4       = "ipv4"
+	flagIPv6       = "ipv6"
+	flagUnixSocket = "unix-socket"
+)
+
+const unixScheme = "unix:"
+
+// Split a URL like unix:/var/run/docker.sock:/v1.41/info into
+// the socket path and a URL for the request sent over the socket
+func splitUnixURL(rawURL string) (socket string, url string) {
+	rest := strings.TrimPrefix(rawURL, unixScheme)
+	if i := strings.Index(rest, ":"); i >= 0 {
+		socket, url = rest[:i], rest[i+1:]
+	} else {
+		socket = rest
+	}
+	if !strings.HasPrefix(url, "/") {
+		url = "/" + url
+	}
+	return socket, "http://localhost" + url
+}
+
+// Parse the value of -resolve into a map of host:port to addr:port
+// Multiple entries are separated by commas, e.g. "a.com:443:10.0.0.1,b.com:80:::1"
+func parseResolve(value string) (map[string]string, error) {
+	pins := map[string]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, errors.New("-resolve expects host:port:addr, got " + entry)
+		}
+		addr := strings.TrimSuffix(strings.TrimPrefix(parts[2], "["), "]")
+		if net.ParseIP(addr) == nil {
+			return nil, errors.New("-resolve expects an IP address, got " + parts[2])
+		}
+		pins[net.JoinHostPort(strings.ToLower(parts[0]), parts[1])] = net.JoinHostPort(addr, parts[1])
+	}
+	return pins, nil
+}
+
+// Create the transport for a request based on its flags
+func newTransport(flags map[string]string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if _, prs := flags[flagInsecureSkipVerify]; prs {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	if socket := flags[flagUnixSocket]; socket != "" {
+		// every connection goes to the socket, proxies included
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return transport, nil
+	}
+
+	var pins map[string]string
+	if value, prs := flags[flagResolve]; prs {
+		var err error
+		if pins, err = parseResolve(value); err != nil {
+			return nil, err
+		}
+	}
+
+	_, ipv4 := flags[flagIPv4]
+	_, ipv6 := flags[flagIPv6]
+	if ipv4 && ipv6 {
+		return nil, errors.New("-ipv4 and -ipv6 cannot be used together")
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// TLS server name and Host header come from the URL, so only the dialed address changes
+		if pinned, prs := pins[strings.ToLower(addr)]; prs {
+			addr = pinned
+		}
+		if ipv4 {
+			network = "tcp4"
+		} else if ipv6 {
+			network = "tcp6"
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return transport, nil
+}
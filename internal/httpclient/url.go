@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+)
+
+var flagScheme = "scheme"
+
+// Reports whether the host is served from this machine or a private network
+func isLocalHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// Prepend a scheme to URLs without one
+// The scheme is taken from -scheme, or is http for sockets, local and private hosts
+// and the client's default, or https, for other hosts
+func (c *Client) normalizeURL(rawURL string, flags map[string]string) (string, error) {
+	if strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, "http://") {
+		return rawURL, nil
+	}
+
+	scheme, prs := flags[flagScheme]
+	if prs {
+		if scheme != "http" && scheme != "https" {
+			return "", errors.New("-scheme expects http or https")
+		}
+	} else if _, prs := flags[flagUnixSocket]; prs {
+		// services behind sockets rarely speak TLS
+		scheme = "http"
+	} else if u, err := url.Parse("http://" + rawURL); err == nil && isLocalHost(u.Hostname()) {
+		scheme = "http"
+	} else if c.DefaultScheme != "" {
+		scheme = c.DefaultScheme
+	} else {
+		scheme = "https"
+	}
+
+	return scheme + "://" + rawURL, nil
+}
+
+// Append query parameters to the URL, percent-encoding names and values
+func addQuery(u *url.URL, params []parser.Param) {
+	if len(params) == 0 {
+		return
+	}
+
+	values := url.Values{}
+	for _, p := range params {
+		values.Add(p.Name, p.Value)
+	}
+	if u.RawQuery == "" {
+		u.RawQuery = values.Encode()
+	} else {
+		u.RawQuery += "&" + values.Encode()
+	}
+}
+
+// Format query parameters as they were written, without encoding
+func formatQuery(params []parser.Param) []string {
+	lines := make([]string, 0, len(params))
+	for i, p := range params {
+		prefix := "&"
+		if i == 0 {
+			prefix = "?"
+		}
+		lines = append(lines, prefix+p.Name+"="+p.Value)
+	}
+	return lines
+}
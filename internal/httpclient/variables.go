@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+
+	"github.com/ramitmittal/hitman/internal/store"
+)
+
+var flagEnv = "env"
+
+// Returns the name of the environment picked by -env or the client's default
+func (c *Client) environmentName(flags map[string]string) string {
+	if env, prs := flags[flagEnv]; prs && !c.forceEnvironment {
+		return env
+	}
+	return c.Environment
+}
+
+// Returns the names of the environments that requests can use, sorted
+func (c *Client) Environments() ([]string, error) {
+	environments, err := c.loadEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(environments))
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Returns the global environments merged with those of the project
+func (c *Client) loadEnvironments() (map[string]store.Environment, error) {
+	var files []string
+	if c.ProjectDir != "" {
+		files = store.ProjectEnvironmentFiles(c.ProjectDir)
+	}
+	return store.LoadEnvironments(files...)
+}
+
+// Returns the environment picked by -env or the client's default
+// Returns an empty environment when none is picked
+func (c *Client) environment(flags map[string]string) (store.Environment, error) {
+	name := c.environmentName(flags)
+	if name == "" {
+		return store.Environment{Variables: map[string]string{}}, nil
+	}
+
+	environments, err := c.loadEnvironments()
+	if err != nil {
+		return store.Environment{}, err
+	}
+	env, prs := environments[name]
+	if !prs {
+		return store.Environment{}, errors.New("unknown environment " + name)
+	}
+	return env, nil
+}
+
+// Returns the variables of the environment picked by -env or the client's default
+func (c *Client) variables(flags map[string]string) (map[string]string, error) {
+	env, err := c.environment(flags)
+	return env.Variables, err
+}
+
+// Resolve a relative file path against the client's base directory
+func (c *Client) resolvePath(p string) string {
+	if p == "" || filepath.IsAbs(p) || c.BaseDir == "" {
+		return p
+	}
+	return filepath.Join(c.BaseDir, p)
+}
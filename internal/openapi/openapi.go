@@ -0,0 +1,498 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variable that holds the server URL in generated requests
+const BaseURLVariable = "baseUrl"
+
+// Methods of a path item, in the order that their requests are generated
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// An OpenAPI 3 document, with the fields needed to write requests
+type Document struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []server             `yaml:"servers"`
+	Paths      map[string]*pathItem `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*schema      `yaml:"schemas"`
+		Parameters    map[string]*parameter   `yaml:"parameters"`
+		RequestBodies map[string]*requestBody `yaml:"requestBodies"`
+	} `yaml:"components"`
+}
+
+type server struct {
+	URL       string `yaml:"url"`
+	Variables map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"variables"`
+}
+
+// Operations of a path, keyed by lower case method
+type pathItem struct {
+	Parameters []*parameter `yaml:"parameters"`
+	Operations map[string]*operation
+}
+
+func (p *pathItem) UnmarshalYAML(node *yaml.Node) error {
+	var item struct {
+		Parameters []*parameter `yaml:"parameters"`
+		Get        *operation   `yaml:"get"`
+		Put        *operation   `yaml:"put"`
+		Post       *operation   `yaml:"post"`
+		Delete     *operation   `yaml:"delete"`
+		Options    *operation   `yaml:"options"`
+		Head       *operation   `yaml:"head"`
+		Patch      *operation   `yaml:"patch"`
+		Trace      *operation   `yaml:"trace"`
+	}
+	if err := node.Decode(&item); err != nil {
+		return err
+	}
+
+	p.Parameters = item.Parameters
+	p.Operations = map[string]*operation{}
+	for method, op := range map[string]*operation{
+		"get": item.Get, "put": item.Put, "post": item.Post, "delete": item.Delete,
+		"options": item.Options, "head": item.Head, "patch": item.Patch, "trace": item.Trace,
+	} {
+		if op != nil {
+			p.Operations[method] = op
+		}
+	}
+	return nil
+}
+
+type operation struct {
+	OperationID string       `yaml:"operationId"`
+	Summary     string       `yaml:"summary"`
+	Parameters  []*parameter `yaml:"parameters"`
+	RequestBody *requestBody `yaml:"requestBody"`
+}
+
+type parameter struct {
+	Ref      string      `yaml:"$ref"`
+	Name     string      `yaml:"name"`
+	In       string      `yaml:"in"`
+	Required bool        `yaml:"required"`
+	Example  interface{} `yaml:"example"`
+	Schema   *schema     `yaml:"schema"`
+}
+
+type requestBody struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema   *schema     `yaml:"schema"`
+	Example  interface{} `yaml:"example"`
+	Examples map[string]struct {
+		Value interface{} `yaml:"value"`
+	} `yaml:"examples"`
+}
+
+type schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       interface{}        `yaml:"type"`
+	Format     string             `yaml:"format"`
+	Properties map[string]*schema `yaml:"properties"`
+	Items      *schema            `yaml:"items"`
+	Example    interface{}        `yaml:"example"`
+	Default    interface{}        `yaml:"default"`
+	Enum       []interface{}      `yaml:"enum"`
+	AllOf      []*schema          `yaml:"allOf"`
+	OneOf      []*schema          `yaml:"oneOf"`
+	AnyOf      []*schema          `yaml:"anyOf"`
+}
+
+// An operation of a document and the request written for it
+type Operation struct {
+	// operationId, or the method and path when the operation has none
+	ID      string
+	Method  string
+	Path    string
+	Summary string
+
+	// request in the .http format, using {{baseUrl}} for the server
+	Request string
+}
+
+// Read an OpenAPI 3 document in JSON or YAML
+func Load(file string) (*Document, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse an OpenAPI 3 document in JSON or YAML
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, errors.New("only OpenAPI 3 documents are supported")
+	}
+	return &doc, nil
+}
+
+// Returns the URL of the first server, with its variables set to their defaults
+func (d *Document) BaseURL() string {
+	if len(d.Servers) == 0 {
+		return "http://localhost"
+	}
+	url := d.Servers[0].URL
+	for name, variable := range d.Servers[0].Variables {
+		url = strings.ReplaceAll(url, "{"+name+"}", variable.Default)
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// Returns the operations of the document sorted by path, with a request for each
+func (d *Document) Operations() []Operation {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ops []Operation
+	for _, path := range paths {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range methods {
+			op, prs := item.Operations[method]
+			if !prs {
+				continue
+			}
+			id := op.OperationID
+			if id == "" {
+				id = strings.ToUpper(method) + " " + path
+			}
+			ops = append(ops, Operation{
+				ID:      id,
+				Method:  strings.ToUpper(method),
+				Path:    path,
+				Summary: op.Summary,
+				Request: d.request(method, path, item, op),
+			})
+		}
+	}
+	return ops
+}
+
+// Returns a request file with the server URL and a request for each operation
+func (d *Document) RequestFile() string {
+	var file strings.Builder
+	file.WriteString("@" + BaseURLVariable + " = " + d.BaseURL() + "\n")
+	for _, op := range d.Operations() {
+		file.WriteString("\n###\n")
+		file.WriteString(op.Request)
+	}
+	return file.String()
+}
+
+// Write the request for an operation
+func (d *Document) request(method string, path string, item *pathItem, op *operation) string {
+	// parameters of the operation override those of the path
+	var params []*parameter
+	seen := map[string]bool{}
+	for _, list := range [][]*parameter{op.Parameters, item.Parameters} {
+		for _, p := range list {
+			p = d.refParameter(p)
+			if p == nil || seen[p.In+" "+p.Name] {
+				continue
+			}
+			seen[p.In+" "+p.Name] = true
+			params = append(params, p)
+		}
+	}
+
+	contentType, body, raw := d.body(op.RequestBody)
+
+	var lines []string
+	if op.OperationID != "" {
+		lines = append(lines, "# @name "+op.OperationID)
+	}
+	if op.Summary != "" {
+		lines = append(lines, "# "+strings.ReplaceAll(op.Summary, "\n", " "))
+	}
+	for _, in := range []string{"query", "header", "cookie"} {
+		var optional []string
+		for _, p := range params {
+			if p.In == in && !p.Required {
+				optional = append(optional, p.Name)
+			}
+		}
+		if len(optional) > 0 {
+			lines = append(lines, "# optional "+in+": "+strings.Join(optional, ", "))
+		}
+	}
+
+	url := "{{" + BaseURLVariable + "}}" + path
+	for _, p := range params {
+		if p.In == "path" {
+			url = strings.ReplaceAll(url, "{"+p.Name+"}", "{{"+p.Name+"}}")
+		}
+	}
+	lines = append(lines, strings.ToUpper(method)+" "+url)
+
+	// values are quoted only for requests in hitman's syntax, since a raw body makes the request an .http one
+	quote := !raw
+	sep := "?"
+	for _, p := range params {
+		if p.In == "query" && p.Required {
+			lines = append(lines, "    "+sep+p.Name+"="+d.parameterValue(p, quote))
+			sep = "&"
+		}
+	}
+	if contentType != "" {
+		lines = append(lines, "Content-Type: "+contentType)
+	}
+	for _, p := range params {
+		if p.In == "header" && p.Required {
+			lines = append(lines, p.Name+": "+d.parameterValue(p, quote))
+		}
+	}
+	if body != "" {
+		if raw {
+			lines = append(lines, "")
+		}
+		lines = append(lines, body)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Returns the parameter that a $ref points to, or the parameter itself
+func (d *Document) refParameter(p *parameter) *parameter {
+	for i := 0; p != nil && p.Ref != "" && i < 10; i++ {
+		p = d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	}
+	return p
+}
+
+// Returns the example of a parameter, or a variable of the same name when it has none
+func (d *Document) parameterValue(p *parameter, quote bool) string {
+	example := p.Example
+	if example == nil && p.Schema != nil {
+		schema := d.refSchema(p.Schema)
+		if schema.Example != nil {
+			example = schema.Example
+		} else if schema.Default != nil {
+			example = schema.Default
+		}
+	}
+	if example == nil {
+		return "{{" + p.Name + "}}"
+	}
+	value := fmt.Sprint(example)
+	if quote && strings.ContainsAny(value, " :#") && !strings.Contains(value, `"`) {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// Returns the content type and an example body for a request body, and whether the body is written as it is
+// JSON is preferred over forms; other content types are left for the user to write
+func (d *Document) body(rb *requestBody) (contentType string, body string, raw bool) {
+	for i := 0; rb != nil && rb.Ref != "" && i < 10; i++ {
+		rb = d.Components.RequestBodies[strings.TrimPrefix(rb.Ref, "#/components/requestBodies/")]
+	}
+	if rb == nil || len(rb.Content) == 0 {
+		return "", "", false
+	}
+
+	types := make([]string, 0, len(rb.Content))
+	for t := range rb.Content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		if t == "application/json" || strings.HasSuffix(t, "+json") {
+			example := d.mediaExample(rb.Content[t])
+			if example == nil {
+				return t, "", false
+			}
+			var body bytes.Buffer
+			encoder := json.NewEncoder(&body)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(example); err != nil {
+				return t, "", false
+			}
+			return t, strings.TrimSpace(body.String()), true
+		}
+	}
+	for _, t := range types {
+		if t == "application/x-www-form-urlencoded" {
+			return t, d.formItems(rb.Content[t]), false
+		}
+	}
+	return types[0], "# " + types[0] + " body", false
+}
+
+// Returns the example of a media type, or one generated from its schema
+func (d *Document) mediaExample(media mediaType) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+	names := make([]string, 0, len(media.Examples))
+	for name := range media.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value := media.Examples[name].Value; value != nil {
+			return value
+		}
+	}
+	return d.example(media.Schema, nil)
+}
+
+// Returns a form body as request items, one field per line
+func (d *Document) formItems(media mediaType) string {
+	fields, ok := d.mediaExample(media).(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var items []string
+	for _, name := range names {
+		value := fmt.Sprint(fields[name])
+		if _, isString := fields[name].(string); !isString {
+			if encoded, err := json.Marshal(fields[name]); err == nil {
+				value = string(encoded)
+			}
+		}
+		if value == "" || strings.ContainsAny(value, " :#") && !strings.Contains(value, `"`) {
+			value = `"` + value + `"`
+		}
+		items = append(items, name+"="+value)
+	}
+	return strings.Join(items, "\n")
+}
+
+// Returns the schema that a $ref points to, or the schema itself
+func (d *Document) refSchema(s *schema) *schema {
+	for i := 0; s != nil && s.Ref != "" && i < 10; i++ {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if s == nil {
+		return &schema{}
+	}
+	return s
+}
+
+// Returns the type of a schema; the first type other than null for OpenAPI 3.1 type lists
+func (s *schema) typ() string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if v, ok := v.(string); ok && v != "null" {
+				return v
+			}
+		}
+	}
+	if len(s.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// Returns an example value for a schema
+// A schema that refers to itself is left out where it repeats
+func (d *Document) example(s *schema, refs map[string]bool) interface{} {
+	if s != nil && s.Ref != "" {
+		if refs[s.Ref] {
+			return nil
+		}
+		inner := map[string]bool{s.Ref: true}
+		for ref := range refs {
+			inner[ref] = true
+		}
+		refs = inner
+	}
+
+	s = d.refSchema(s)
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.OneOf) > 0:
+		return d.example(s.OneOf[0], refs)
+	case len(s.AnyOf) > 0:
+		return d.example(s.AnyOf[0], refs)
+	case len(s.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, part := range s.AllOf {
+			if fields, ok := d.example(part, refs).(map[string]interface{}); ok {
+				for k, v := range fields {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	switch s.typ() {
+	case "object":
+		fields := map[string]interface{}{}
+		for name, property := range s.Properties {
+			if value := d.example(property, refs); value != nil {
+				fields[name] = value
+			}
+		}
+		return fields
+	case "array":
+		if item := d.example(s.Items, refs); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "1970-01-01T00:00:00Z"
+		case "date":
+			return "1970-01-01"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "email":
+			return "user@example.com"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	}
+	return nil
+}
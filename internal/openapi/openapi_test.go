@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+)
+
+const petstore = `openapi: 3.0.3
+servers:
+  - url: https://{region}.example.com/v1/
+    variables:
+      region:
+        default: eu
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: species
+          in: query
+          required: true
+          example: cat
+        - $ref: '#/components/parameters/Tenant'
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+    delete:
+      summary: Delete a pet
+  /login:
+    post:
+      operationId: login
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                user:
+                  type: string
+                  example: alice
+                remember:
+                  type: boolean
+components:
+  parameters:
+    Tenant:
+      name: X-Tenant
+      in: header
+      required: true
+      schema:
+        type: string
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+          example: Rex
+        born:
+          type: string
+          format: date
+        tags:
+          type: array
+          items:
+            type: string
+            enum: [good, loud]
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      allOf:
+        - type: object
+          properties:
+            id:
+              type: integer
+        - type: object
+          properties:
+            pets:
+              type: array
+              items:
+                $ref: '#/components/schemas/Pet'
+`
+
+func TestOperations(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if doc.BaseURL() != "https://eu.example.com/v1" {
+		t.Log(doc.BaseURL())
+		t.Fail()
+	}
+
+	var tests = []struct {
+		id      string
+		method  string
+		url     string
+		headers map[string]string
+		body    string
+	}{
+		{"login", "POST", "{{baseUrl}}/login", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, ""},
+		{"listPets", "GET", "{{baseUrl}}/pets", map[string]string{"X-Tenant": "{{X-Tenant}}"}, ""},
+		{"createPet", "POST", "{{baseUrl}}/pets", map[string]string{"Content-Type": "application/json"}, "Rex"},
+		{"DELETE /pets/{petId}", "DELETE", "{{baseUrl}}/pets/{{petId}}", map[string]string{}, ""},
+	}
+
+	ops := doc.Operations()
+	if len(ops) != len(tests) {
+		t.Log(len(ops))
+		t.FailNow()
+	}
+	for i, test := range tests {
+		op := ops[i]
+		if op.ID != test.id || op.Method != test.method {
+			t.Log(op.ID, op.Method)
+			t.Fail()
+			continue
+		}
+
+		// the requests are valid
+		result, err := parser.ParseRequest(op.Request)
+		if err != nil {
+			t.Log(op.Request, err)
+			t.Fail()
+			continue
+		}
+		if result.Method != test.method || result.Url != test.url {
+			t.Log(op.ID, result.Method, result.Url)
+			t.Fail()
+		}
+		for name, value := range test.headers {
+			if result.Headers[name] != value {
+				t.Log(op.ID, result.Headers)
+				t.Fail()
+			}
+		}
+		if !strings.Contains(result.Body.Text, test.body) {
+			t.Log(op.ID, result.Body.Text)
+			t.Fail()
+		}
+	}
+
+	if !strings.Contains(ops[0].Request, `user=alice`) || !strings.Contains(ops[0].Request, `remember=false`) {
+		t.Log(ops[0].Request)
+		t.Fail()
+	}
+	if !strings.Contains(ops[1].Request, "?species=cat") || !strings.Contains(ops[1].Request, "# optional query: limit") {
+		t.Log(ops[1].Request)
+		t.Fail()
+	}
+	if !strings.Contains(ops[2].Request, `"born": "1970-01-01"`) || !strings.Contains(ops[2].Request, `"good"`) || !strings.Contains(ops[2].Request, `"pets": []`) {
+		t.Log(ops[2].Request)
+		t.Fail()
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(`{"openapi": "3.1.0", "paths": {"/health": {"get": {"operationId": "health"}}}}`))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	file := doc.RequestFile()
+	if !strings.HasPrefix(file, "@baseUrl = http://localhost\n") || !strings.Contains(file, "# @name health\nGET {{baseUrl}}/health") {
+		t.Log(file)
+		t.Fail()
+	}
+
+	if _, err := Parse([]byte(`{"swagger": "2.0"}`)); err == nil {
+		t.Fail()
+	}
+}
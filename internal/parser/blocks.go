@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"strings"
+)
+
+// A request in a document that holds several of them
+type Block struct {
+	// name given with a # @name comment
+	Name string
+
+	// text of the request, without the separator
+	Text string
+
+	// index of the first and last line of the block in the document
+	StartLine int
+	EndLine   int
+}
+
+// Reports whether the line separates two requests
+func isSeparator(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "###")
+}
+
+// Returns the name set by a line like # @name login
+// .http files may also write // @name login or # @name = login
+func blockName(line string) string {
+	line = strings.TrimSpace(line)
+	if !isComment(line) {
+		return ""
+	}
+	fields := strings.Fields(strings.Replace(strings.TrimLeft(line, "#/"), "=", " ", 1))
+	if len(fields) == 2 && fields[0] == "@name" {
+		return fields[1]
+	}
+	return ""
+}
+
+// Split a document into requests separated by lines starting with ###
+func SplitBlocks(document string) []Block {
+	lines := strings.Split(document, "\n")
+
+	var blocks []Block
+	current := Block{}
+	var text []string
+
+	for i, line := range lines {
+		if isSeparator(line) && i > 0 {
+			current.Text = strings.Join(text, "\n")
+			current.EndLine = i - 1
+			blocks = append(blocks, current)
+
+			current = Block{StartLine: i}
+			text = nil
+			continue
+		}
+		if isSeparator(line) {
+			continue
+		}
+		if name := blockName(line); name != "" && current.Name == "" {
+			current.Name = name
+		}
+		text = append(text, line)
+	}
+
+	current.Text = strings.Join(text, "\n")
+	current.EndLine = len(lines) - 1
+	return append(blocks, current)
+}
+
+// Returns the block that contains the line
+func BlockAt(blocks []Block, line int) Block {
+	for _, block := range blocks {
+		if line >= block.StartLine && line <= block.EndLine {
+			return block
+		}
+	}
+	return blocks[len(blocks)-1]
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Methods that may start the request line of an .http file
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+	"OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+var (
+	// a file variable like @host = example.com
+	variableLine = regexp.MustCompile(`^@([A-Za-z0-9_.-]+)\s*=\s*(.*)$`)
+
+	// a header like Content-Type: application/json
+	headerLine = regexp.MustCompile(`^([A-Za-z0-9!#$%&'*+.^_|~-]+)\s*:\s*(.*)$`)
+
+	// the protocol at the end of a request line
+	protocol = regexp.MustCompile(`^HTTP/[0-9.]+$`)
+)
+
+// Reports whether the line is a comment in an .http file
+func isComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// Returns the variables defined with lines like @host = example.com in an .http file
+func FileVariables(text string) map[string]string {
+	variables := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		if m := variableLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			variables[m[1]] = strings.TrimSpace(m[2])
+		}
+	}
+	return variables
+}
+
+// Parse a request written in the .http format of the JetBrains and VS Code REST clients
+//
+//	GET https://example.com/users?page=1 HTTP/1.1
+//	Content-Type: application/json
+//
+//	{"name": "hitman"}
+//
+// A body of < ./path/to/file is read from the file and response handlers are ignored with a warning
+func ParseHTTPFile(text string) (Result, error) {
+	result := Result{
+		Headers: map[string]string{},
+		Flags:   map[string]string{},
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// the request line comes after comments and variables
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !isComment(line) && !variableLine.MatchString(line) {
+			break
+		}
+	}
+	if i == len(lines) {
+		return result, errors.New("no request line")
+	}
+
+	fields := strings.Fields(lines[i])
+	if httpMethods[fields[0]] {
+		result.Method, fields = fields[0], fields[1:]
+	} else {
+		result.Method = "GET"
+	}
+	if len(fields) == 2 && protocol.MatchString(fields[1]) {
+		fields = fields[:1]
+	}
+	if len(fields) != 1 {
+		return result, errors.New("invalid request line: " + lines[i])
+	}
+	result.Url = fields[0]
+	i++
+
+	// the query may continue on indented lines
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || lines[i][0] != ' ' && lines[i][0] != '\t' || line[0] != '?' && line[0] != '&' {
+			break
+		}
+		if fields := strings.Fields(line); len(fields) == 2 && protocol.MatchString(fields[1]) {
+			line = fields[0]
+		}
+		result.Url += line
+	}
+
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if isComment(line) {
+			continue
+		}
+		m := headerLine.FindStringSubmatch(line)
+		if m == nil {
+			return result, errors.New("invalid header: " + line)
+		}
+		result.Headers[m[1]] = strings.TrimSpace(m[2])
+	}
+
+	var body []string
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "> ") || line == ">" {
+			result.Warnings = append(result.Warnings, "response handler ignored")
+			if strings.Contains(line, "{%") && !strings.Contains(line, "%}") {
+				// skip the rest of the script
+				for i++; i < len(lines) && !strings.Contains(lines[i], "%}"); i++ {
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "<> ") {
+			result.Warnings = append(result.Warnings, "response reference "+strings.TrimSpace(line[3:])+" ignored")
+			continue
+		}
+		body = append(body, lines[i])
+	}
+
+	// blank lines around the body are not part of it
+	for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+	for len(body) > 0 && strings.TrimSpace(body[0]) == "" {
+		body = body[1:]
+	}
+
+	if len(body) == 1 && strings.HasPrefix(strings.TrimSpace(body[0]), "<") {
+		file := strings.TrimSpace(body[0])
+		if strings.HasPrefix(file, "<@") {
+			result.Body.Substitute = true
+			file = file[2:]
+		} else {
+			file = file[1:]
+		}
+		result.Body.File = strings.TrimSpace(file)
+	} else if len(body) > 0 {
+		result.Body.Text = strings.Join(body, "\n")
+	}
+	return result, nil
+}
+
+// Reports whether a request is written in the .http format: its request line ends in a protocol
+// like HTTP/1.1 or a blank line separates a raw body from the headers
+// Request items follow the headers without a blank line in hitman's syntax
+func isHTTPFile(lines []string) bool {
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !isComment(line) && !variableLine.MatchString(line) {
+			break
+		}
+	}
+	if i == len(lines) {
+		return false
+	}
+	if fields := strings.Fields(lines[i]); protocol.MatchString(fields[len(fields)-1]) {
+		return true
+	}
+
+	blank := false
+	for _, line := range lines[i+1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			blank = true
+		case isComment(line) || variableLine.MatchString(line):
+		case blank:
+			return !strings.ContainsAny(line[:1], "-?&") && !headerLine.MatchString(line)
+		}
+	}
+	return false
+}
+
+// Parse a request in hitman's syntax, or in the .http format when it is written in it or is not valid hitman syntax
+// Variable definitions are skipped in both
+func ParseRequest(text string) (Result, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if isHTTPFile(lines) {
+		return ParseHTTPFile(text)
+	}
+	for i, line := range lines {
+		if variableLine.MatchString(strings.TrimSpace(line)) {
+			lines[i] = ""
+		}
+	}
+
+	result, err := Parse([]byte(strings.Join(lines, "\n")))
+	if err == nil {
+		return result, nil
+	}
+	if compat, compatErr := ParseHTTPFile(text); compatErr == nil {
+		return compat, nil
+	}
+	return result, err
+}
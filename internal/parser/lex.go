@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"errors"
 	"strings"
 	"unicode/utf8"
@@ -8,11 +9,47 @@ import (
 
 //go:generate go run golang.org/x/tools/cmd/goyacc -l -o parser.go parser.y
 
+// A query parameter written as ?name=value or &name=value
+type Param struct {
+	Name  string
+	Value string
+}
+
+// An httpie-style request item like name=value, count:=42 or q==search
+// File parts of multipart bodies are written as name < ./path/to/file
+type Item struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// Operators that separate the name and value of a request item
+// Longer operators come first so that they win over their prefixes
+var itemOperators = []string{":=@", ":=", "==", "=@", "=", "@", "<"}
+
+// A raw request body written as < ./path/to/file
+type Body struct {
+	// path of the file that contains the body
+	File string
+
+	// replace {{variables}} in the file before sending it; written as <@ ./path/to/file
+	Substitute bool
+
+	// body written out after the headers, in .http files
+	Text string
+}
+
 type Result struct {
 	Method  string
 	Url     string
+	Query   []Param
 	Headers map[string]string
+	Items   []Item
+	Body    Body
 	Flags   map[string]string
+
+	// parts of the request that were ignored
+	Warnings []string
 }
 
 func Parse(input []byte) (Result, error) {
@@ -23,15 +60,75 @@ func Parse(input []byte) (Result, error) {
 	return l.result, l.err
 }
 
+// A flag and the value that follows it on the same line
+type flag struct {
+	name  string
+	value string
+}
+
 type lex struct {
 	input  []byte
 	result Result
 	err    error
 
 	position int
+
+	// number of tokens returned so far
+	tokens int
+
+	// true from the colon of a header till the end of its line
+	inHeader bool
 }
 
 func (l *lex) Lex(lval *yySymType) int {
+	token := l.next(lval)
+	l.tokens++
+	if token == ':' {
+		l.inHeader = true
+	}
+	return token
+}
+
+// Returns the operator of a request item at the current position and its length, if any
+// Spaces are allowed before the < of file parts
+func (l *lex) itemOperator() (string, int) {
+	rest := l.input[l.position:]
+	for _, op := range itemOperators {
+		if bytes.HasPrefix(rest, []byte(op)) {
+			return op, len(op)
+		}
+	}
+	if trimmed := bytes.TrimLeft(rest, " "); bytes.HasPrefix(trimmed, []byte("<")) {
+		return "<", len(rest) - len(trimmed) + 1
+	}
+	return "", 0
+}
+
+// Copy a {{template}} at the current position into str as it is
+// Templates may contain spaces, colons and quotes, e.g. {{$base64 "user:pass"}}
+// Returns false if there is no template at the current position
+func (l *lex) template(str *strings.Builder) bool {
+	rest := l.input[l.position:]
+	if !bytes.HasPrefix(rest, []byte("{{")) {
+		return false
+	}
+	end := bytes.Index(rest, []byte("}}"))
+	if end < 0 {
+		return false
+	}
+	str.Write(rest[:end+2])
+	l.position += end + 2
+	return true
+}
+
+// Discard spaces till the next character
+func (l *lex) skipSpaces() {
+	for l.position < len(l.input) && l.input[l.position] == ' ' {
+		l.position++
+	}
+}
+
+func (l *lex) next(lval *yySymType) int {
 	r, size := utf8.DecodeRune(l.input[l.position:])
 	l.position += size
 
@@ -40,7 +137,10 @@ func (l *lex) Lex(lval *yySymType) int {
 	}
 	if r == ' ' || r == '\n' {
 		// discard spaces and newlines
-		return l.Lex(lval)
+		if r == '\n' {
+			l.inHeader = false
+		}
+		return l.next(lval)
 	}
 	if r == ':' {
 		return int(r)
@@ -54,15 +154,19 @@ func (l *lex) Lex(lval *yySymType) int {
 				return 0
 			}
 			if r1 == '\n' {
+				l.inHeader = false
 				break
 			}
 		}
-		return l.Lex(lval)
+		return l.next(lval)
 	}
 	if r == '"' {
 		// gather everything till closing "
 		var str strings.Builder
 		for {
+			if l.template(&str) {
+				continue
+			}
 			r1, size1 := utf8.DecodeRune(l.input[l.position:])
 			l.position += size1
 			if size1 == 0 {
@@ -75,6 +179,25 @@ func (l *lex) Lex(lval *yySymType) int {
 			str.WriteRune(r1)
 		}
 	}
+	if r == '<' {
+		// gather the path of the body file
+		if r1, size1 := utf8.DecodeRune(l.input[l.position:]); r1 == '@' {
+			l.position += size1
+			lval.body.Substitute = true
+		}
+		l.skipSpaces()
+		lval.body.File = l.value()
+		return BodyFile
+	}
+	if r == '?' || r == '&' {
+		// gather the name till = and the value till space or newline
+		lval.param = Param{Name: l.word("=")}
+		if r1, size1 := utf8.DecodeRune(l.input[l.position:]); r1 == '=' {
+			l.position += size1
+			lval.param.Value = l.value()
+		}
+		return QueryParam
+	}
 	if r == '-' {
 		// gather everything till space or newline
 		var str strings.Builder
@@ -82,18 +205,38 @@ func (l *lex) Lex(lval *yySymType) int {
 			r1, size1 := utf8.DecodeRune(l.input[l.position:])
 			l.position += size1
 			if r1 == '\n' || r1 == ' ' || r1 == ':' || size1 == 0 {
-				lval.val = str.String()
+				lval.flag = flag{name: str.String()}
+				if r1 == ' ' {
+					lval.flag.value = l.flagValue()
+				}
 				return Flag
 			}
 			str.WriteRune(r1)
 		}
 	}
 
+	// method, URL and words on the line of a header are never request items
+	itemAllowed := l.tokens >= 2 && !l.inHeader
+
 	// gather everything till one of the aforementioned characters
 	var str strings.Builder
-	str.WriteRune(r)
+	l.position -= size
 
 	for {
+		if l.template(&str) {
+			continue
+		}
+		if itemAllowed && str.Len() > 0 {
+			if op, size := l.itemOperator(); op != "" {
+				l.position += size
+				if op == "<" {
+					l.skipSpaces()
+				}
+				lval.item = Item{Name: str.String(), Op: op, Value: l.value()}
+				return RequestItem
+			}
+		}
+
 		r1, size1 := utf8.DecodeRune(l.input[l.position:])
 		if r1 == '\n' || r1 == ' ' || r1 == ':' || size1 == 0 {
 			lval.val = str.String()
@@ -105,6 +248,62 @@ func (l *lex) Lex(lval *yySymType) int {
 	}
 }
 
+// Returns the value following a flag on the same line, if any
+// Values may contain colons; quotes are required for spaces
+func (l *lex) flagValue() string {
+	for {
+		r, size := utf8.DecodeRune(l.input[l.position:])
+		if r != ' ' {
+			if r == '-' || r == '\n' || r == '#' || size == 0 {
+				return ""
+			}
+			break
+		}
+		l.position += size
+	}
+
+	return l.value()
+}
+
+// Returns a value that ends at a space or newline unless it is quoted
+// Unlike other words, values may contain colons
+func (l *lex) value() string {
+	r, size := utf8.DecodeRune(l.input[l.position:])
+	if r != '"' {
+		return l.word("")
+	}
+	l.position += size
+
+	var str strings.Builder
+	for {
+		if l.template(&str) {
+			continue
+		}
+		r, size := utf8.DecodeRune(l.input[l.position:])
+		l.position += size
+		if size == 0 || r == '"' {
+			return str.String()
+		}
+		str.WriteRune(r)
+	}
+}
+
+// Returns everything till a space, newline or one of the stop characters
+func (l *lex) word(stop string) string {
+	var str strings.Builder
+	for {
+		if l.template(&str) {
+			continue
+		}
+		r, size := utf8.DecodeRune(l.input[l.position:])
+		if size == 0 || r == ' ' || r == '\n' || strings.ContainsRune(stop, r) {
+			return str.String()
+		}
+		l.position += size
+		str.WriteRune(r)
+	}
+}
+
 func (l *lex) Error(s string) {
 	l.err = errors.New(s)
 }
@@ -13,10 +13,19 @@ type yySymType struct {
 	val    string
 	hh     map[string]string
 	ff     map[string]string
+	flag   flag
+	param  Param
+	params []Param
+	item   Item
+	items  []Item
+	body   Body
 }
 
 const S = 57346
 const Flag = 57347
+const QueryParam = 57348
+const RequestItem = 57349
+const BodyFile = 57350
 
 var yyToknames = [...]string{
 	"$end",
@@ -24,6 +33,9 @@ var yyToknames = [...]string{
 	"$unk",
 	"S",
 	"Flag",
+	"QueryParam",
+	"RequestItem",
+	"BodyFile",
 	"':'",
 }
 var yyStatenames = [...]string{}
@@ -40,38 +52,41 @@ var yyExca = [...]int{
 
 const yyPrivate = 57344
 
-const yyLast = 10
+const yyLast = 16
 
 var yyAct = [...]int{
 
-	9, 8, 10, 7, 3, 2, 5, 6, 4, 1,
+	13, 11, 12, 6, 16, 15, 9, 3, 2, 10,
+	7, 4, 14, 8, 5, 1,
 }
 var yyPact = [...]int{
 
-	1, -1000, 0, -1000, -1, -4, -1000, -6, -1000, -2,
-	-1000,
+	4, -1000, 3, -1000, -3, 2, -1000, -6, -1000, -9,
+	-1000, -1000, -1000, 1, -1, -1000, -1000,
 }
 var yyPgo = [...]int{
 
-	0, 9, 8, 7, 6,
+	0, 15, 14, 13, 12, 11, 10, 9,
 }
 var yyR1 = [...]int{
 
-	0, 1, 2, 2, 3, 4, 4,
+	0, 1, 5, 5, 2, 2, 3, 6, 6, 7,
+	7, 4, 4,
 }
 var yyR2 = [...]int{
 
-	0, 4, 2, 0, 3, 2, 0,
+	0, 7, 2, 0, 2, 0, 3, 2, 0, 1,
+	0, 2, 0,
 }
 var yyChk = [...]int{
 
-	-1000, -1, 4, 4, -2, -4, -3, 4, 5, 6,
-	4,
+	-1000, -1, 4, 4, -5, -2, 6, -6, -3, 4,
+	-7, 7, 8, 9, -4, 4, 5,
 }
 var yyDef = [...]int{
 
-	0, -2, 0, 3, 6, 1, 2, 0, 5, 0,
-	4,
+	0, -2, 0, 3, 5, 8, 2, 10, 4, 0,
+	12, 7, 9, 0, 1, 6, 11,
 }
 var yyTok1 = [...]int{
 
@@ -80,11 +95,11 @@ var yyTok1 = [...]int{
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 6,
+	3, 3, 3, 3, 3, 3, 3, 3, 9,
 }
 var yyTok2 = [...]int{
 
-	2, 3, 4, 5,
+	2, 3, 4, 5, 6, 7, 8,
 }
 var yyTok3 = [...]int{
 	0,
@@ -426,35 +441,59 @@ yydefault:
 	switch yynt {
 
 	case 1:
-		yyDollar = yyS[yypt-4 : yypt+1]
+		yyDollar = yyS[yypt-7 : yypt+1]
 		{
-			yyVAL.result = Result{Method: yyDollar[1].val, Url: yyDollar[2].val, Headers: yyDollar[3].hh, Flags: yyDollar[4].ff}
+			yyVAL.result = Result{Method: yyDollar[1].val, Url: yyDollar[2].val, Query: yyDollar[3].params, Headers: yyDollar[4].hh, Items: yyDollar[5].items, Body: yyDollar[6].body, Flags: yyDollar[7].ff}
 			setResult(yylex, yyVAL.result)
 		}
 	case 2:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
-			yyVAL.hh = merge(yyDollar[2].hh, yyDollar[1].hh)
+			yyVAL.params = append(yyDollar[1].params, yyDollar[2].param)
 		}
 	case 3:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 		}
 	case 4:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.hh = merge(yyDollar[2].hh, yyDollar[1].hh)
+		}
+	case 5:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.hh = map[string]string{}
+		}
+	case 6:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.hh = map[string]string{
 				yyDollar[1].val: yyDollar[3].val,
 			}
 		}
-	case 5:
+	case 7:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
-			yyVAL.ff = merge(mapOf(yyDollar[2].val, ""), yyDollar[1].ff)
+			yyVAL.items = append(yyDollar[1].items, yyDollar[2].item)
 		}
-	case 6:
+	case 8:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+		}
+	case 10:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+		}
+	case 11:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.ff = merge(mapOf(yyDollar[2].flag.name, yyDollar[2].flag.value), yyDollar[1].ff)
+		}
+	case 12:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
+			yyVAL.ff = map[string]string{}
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -12,29 +12,45 @@ func setResult(l yyLexer, v Result) {
     val string
     hh map[string]string
     ff map[string]string
+    flag flag
+    param Param
+    params []Param
+    item Item
+    items []Item
+    body Body
 }
 
 %type <result> request
 %type <hh> headers
 %type <hh> header
 %type <ff> flags
+%type <params> query
+%type <items> items
+%type <body> body
 
 %token <val> S
-%token <val> Flag
+%token <flag> Flag
+%token <param> QueryParam
+%token <item> RequestItem
+%token <body> BodyFile
 
 %start request
 
 %%
 
-request: S S headers flags
+request: S S query headers items body flags
     {
-        $$ = Result{Method: $1, Url: $2, Headers: $3, Flags: $4}
+        $$ = Result{Method: $1, Url: $2, Query: $3, Headers: $4, Items: $5, Body: $6, Flags: $7}
         setResult(yylex, $$)
     }
 
+query: query QueryParam
+    { $$ = append($1, $2) }
+| {}
+
 headers: headers header
     { $$ = merge($2, $1) }
-| {}
+| { $$ = map[string]string{} }
 
 header: S ':' S
     {
@@ -43,7 +59,14 @@ header: S ':' S
         }
     }
 
-flags: flags Flag
-    { $$ = merge(mapOf($2, ""), $1)}
+items: items RequestItem
+    { $$ = append($1, $2) }
 | {}
+
+body: BodyFile
+| {}
+
+flags: flags Flag
+    { $$ = merge(mapOf($2.name, $2.value), $1)}
+| { $$ = map[string]string{} }
 %%
\ No newline at end of file
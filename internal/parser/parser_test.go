@@ -42,6 +42,167 @@ func TestValidFlags(t *testing.T) {
 	}
 }
 
+func TestFlagValues(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		flag  string
+		value string
+	}{
+		{"Flag without value", `GET www.ramitmittal.com -no-cookies`, "no-cookies", ""},
+		{"Flag with value", `GET www.ramitmittal.com -session work`, "session", "work"},
+		{"Flag value with colons", `GET www.ramitmittal.com -resolve example.com:443:127.0.0.1`, "resolve", "example.com:443:127.0.0.1"},
+		{"Quoted flag value", `GET www.ramitmittal.com -session "my work"`, "session", "my work"},
+		{"Flag value followed by flag", `GET www.ramitmittal.com -session work -insecure`, "session", "work"},
+		{"Flag followed by comment", `GET www.ramitmittal.com -insecure # no value`, "insecure", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v, err := Parse([]byte(test.input)); err != nil {
+				t.Fail()
+			} else if value, prs := v.Flags[test.flag]; !prs || value != test.value {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	input := `GET www.ramitmittal.com/search
+?q="hello world"
+&redirect=https://example.com/?a=b
+&empty=
+&flag
+Accept: application/json`
+
+	expected := []Param{
+		{"q", "hello world"},
+		{"redirect", "https://example.com/?a=b"},
+		{"empty", ""},
+		{"flag", ""},
+	}
+
+	if v, err := Parse([]byte(input)); err != nil {
+		t.Fail()
+	} else if len(v.Query) != len(expected) {
+		t.Fail()
+	} else if v.Headers["Accept"] != "application/json" {
+		t.Fail()
+	} else {
+		for i, p := range expected {
+			if v.Query[i] != p {
+				t.Log(v.Query[i])
+				t.Fail()
+			}
+		}
+	}
+
+	if _, err := Parse([]byte(`GET www.ramitmittal.com Accept: application/json ?q=1`)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestRequestItems(t *testing.T) {
+	input := `POST www.ramitmittal.com/?a=b
+Cache-Control: max-age=0
+name=hitman
+description="a TUI: HTTP client"
+stars:=42
+q==search
+avatar@./me.png
+readme=@./README.md
+config:=@./config.json
+photo < ./photo.jpg;type=image/jpeg
+icon<./icon.png
+-form`
+
+	expected := []Item{
+		{"name", "=", "hitman"},
+		{"description", "=", "a TUI: HTTP client"},
+		{"stars", ":=", "42"},
+		{"q", "==", "search"},
+		{"avatar", "@", "./me.png"},
+		{"readme", "=@", "./README.md"},
+		{"config", ":=@", "./config.json"},
+		{"photo", "<", "./photo.jpg;type=image/jpeg"},
+		{"icon", "<", "./icon.png"},
+	}
+
+	if v, err := Parse([]byte(input)); err != nil {
+		t.Fail()
+	} else if v.Url != "www.ramitmittal.com/?a=b" {
+		t.Fail()
+	} else if v.Headers["Cache-Control"] != "max-age=0" {
+		t.Fail()
+	} else if _, prs := v.Flags["form"]; !prs {
+		t.Fail()
+	} else if len(v.Items) != len(expected) {
+		t.Fail()
+	} else {
+		for i, item := range expected {
+			if v.Items[i] != item {
+				t.Log(v.Items[i])
+				t.Fail()
+			}
+		}
+	}
+
+	if _, err := Parse([]byte(`POST www.ramitmittal.com name=hitman Accept: application/json`)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestBodyFile(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected Body
+	}{
+		{"Body file", "POST www.ramitmittal.com\nContent-Type: application/json\n< ./payloads/create-order.json", Body{File: "./payloads/create-order.json"}},
+		{"Body file with variables", "POST www.ramitmittal.com\n<@ ./order.json -insecure", Body{File: "./order.json", Substitute: true}},
+		{"Quoted body file", `POST www.ramitmittal.com <"./my order.json"`, Body{File: "./my order.json"}},
+		{"No body file", `POST www.ramitmittal.com`, Body{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v, err := Parse([]byte(test.input)); err != nil {
+				t.Fail()
+			} else if v.Body != test.expected {
+				t.Log(v.Body)
+				t.Fail()
+			}
+		})
+	}
+
+	if _, err := Parse([]byte("POST www.ramitmittal.com < ./a.json < ./b.json")); err == nil {
+		t.Fail()
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	input := `POST {{host}}/orders/{{$randomInt 1 100}}
+?ts={{$timestamp}}
+Authorization: "Basic {{$base64 "user:pass"}}"
+X-Request-Id: {{$uuid}}
+id={{$uuid}}`
+
+	if v, err := Parse([]byte(input)); err != nil {
+		t.Fail()
+	} else if v.Url != "{{host}}/orders/{{$randomInt 1 100}}" {
+		t.Fail()
+	} else if v.Query[0].Value != "{{$timestamp}}" {
+		t.Fail()
+	} else if v.Headers["Authorization"] != `Basic {{$base64 "user:pass"}}` {
+		t.Fail()
+	} else if v.Headers["X-Request-Id"] != "{{$uuid}}" {
+		t.Fail()
+	} else if len(v.Items) != 1 || v.Items[0].Value != "{{$uuid}}" {
+		t.Fail()
+	}
+}
+
 func TestValidInputs(t *testing.T) {
 	var tests = []struct {
 		name  string
@@ -146,6 +307,7 @@ func TestInvalidInputs(t *testing.T) {
 		{"Flags before headers", `GET www.ramitmittal.com -flag1 Cache-Control: "no-cache"`},
 		{"URL with : must be quoted", `GET https://www.ramitmittal.com`},
 		{"Quotes inside header values are not supported", `GET www.ramitmittal.com Accept-Encoding: gzip, "br"`},
+		{"Words on a header line are not request items", "GET www.ramitmittal.com\nAuthorization: Basic abc="},
 	}
 
 	for _, test := range tests {
@@ -158,3 +320,146 @@ func TestInvalidInputs(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitBlocks(t *testing.T) {
+	document := `# @name login
+POST auth.example.com/login user=alice
+###
+GET api.example.com/me
+Authorization: "Bearer {{login.response.body.$.token}}"
+
+### a comment after the separator
+# @name logout
+POST auth.example.com/logout`
+
+	blocks := SplitBlocks(document)
+	if len(blocks) != 3 {
+		t.FailNow()
+	}
+
+	var tests = []struct {
+		line int
+		name string
+		url  string
+	}{
+		{0, "login", "auth.example.com/login"},
+		{1, "login", "auth.example.com/login"},
+		{2, "", "api.example.com/me"},
+		{4, "", "api.example.com/me"},
+		{6, "logout", "auth.example.com/logout"},
+		{8, "logout", "auth.example.com/logout"},
+	}
+
+	for _, test := range tests {
+		block := BlockAt(blocks, test.line)
+		if block.Name != test.name {
+			t.Log(test.line, block.Name)
+			t.Fail()
+		}
+		if v, err := Parse([]byte(block.Text)); err != nil {
+			t.Log(test.line, err)
+			t.Fail()
+		} else if v.Url != test.url {
+			t.Fail()
+		}
+	}
+
+	if blocks := SplitBlocks(`GET www.ramitmittal.com`); len(blocks) != 1 || blocks[0].Text != `GET www.ramitmittal.com` {
+		t.Fail()
+	}
+}
+
+func TestHTTPFile(t *testing.T) {
+	document := `### create a user
+// a comment
+@host = api.example.com
+POST https://{{host}}/users
+    ?page=1
+    &size=10 HTTP/1.1
+Content-Type: application/json
+X-Request-Id : 42
+
+{
+  "name": "hitman"
+}
+
+> {% client.global.set("id", response.body.id); %}
+<> 2024-01-01T000000.200.json`
+
+	result, err := ParseRequest(document)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if result.Method != "POST" || result.Url != "https://{{host}}/users?page=1&size=10" {
+		t.Log(result.Method, result.Url)
+		t.Fail()
+	}
+	if result.Headers["Content-Type"] != "application/json" || result.Headers["X-Request-Id"] != "42" {
+		t.Log(result.Headers)
+		t.Fail()
+	}
+	if result.Body.Text != "{\n  \"name\": \"hitman\"\n}" {
+		t.Log(result.Body.Text)
+		t.Fail()
+	}
+	if len(result.Warnings) != 2 {
+		t.Log(result.Warnings)
+		t.Fail()
+	}
+	if v := FileVariables(document); v["host"] != "api.example.com" {
+		t.Log(v)
+		t.Fail()
+	}
+
+	var tests = []struct {
+		input      string
+		method     string
+		url        string
+		file       string
+		substitute bool
+	}{
+		{"https://example.com", "GET", "https://example.com", "", false},
+		{"DELETE example.com/users/1 HTTP/2", "DELETE", "example.com/users/1", "", false},
+		{"PUT example.com\n\n< ./body.json", "PUT", "example.com", "./body.json", false},
+		{"PUT example.com\n\n<@ ./body.json", "PUT", "example.com", "./body.json", true},
+		{"@id = 1\nGET example.com/{{id}} q==v", "GET", "example.com/{{id}}", "", false},
+	}
+
+	for _, test := range tests {
+		result, err := ParseRequest(test.input)
+		if err != nil {
+			t.Log(test.input, err)
+			t.Fail()
+			continue
+		}
+		if result.Method != test.method || result.Url != test.url || result.Body.File != test.file || result.Body.Substitute != test.substitute {
+			t.Log(test.input, result.Method, result.Url, result.Body)
+			t.Fail()
+		}
+	}
+
+	// a raw body after a blank line is not read as request items
+	form := "POST example.com/login\nContent-Type: application/x-www-form-urlencoded\n\nuser=a&pass=b"
+	if result, err := ParseRequest(form); err != nil || result.Body.Text != "user=a&pass=b" || len(result.Items) != 0 {
+		t.Log(result.Body, result.Items, err)
+		t.Fail()
+	}
+	if result, err := ParseRequest("POST example.com/login HTTP/1.1\nuser=a&pass=b"); err == nil {
+		t.Log(result)
+		t.Fail()
+	}
+	if result, err := ParseRequest("GET example.com\n\n?q=1\n\nXXX: hello"); err != nil || result.Headers["XXX"] != "hello" || len(result.Query) != 1 {
+		t.Log(result, err)
+		t.Fail()
+	}
+	items := "POST example.com/login\nContent-Type: application/x-www-form-urlencoded\nuser=a\n-form\n\n-env staging"
+	if result, err := ParseRequest(items); err != nil || len(result.Items) != 1 || result.Body.Text != "" || result.Flags["env"] != "staging" {
+		t.Log(result, err)
+		t.Fail()
+	}
+
+	if _, err := ParseHTTPFile("GET example.com\nnot a header"); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,535 @@
+package postman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// A Postman collection in the v2.1 format, with the fields needed to write requests
+type Collection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []item     `json:"item"`
+	Variable []keyValue `json:"variable"`
+	Auth     *auth      `json:"auth"`
+	Event    []event    `json:"event"`
+}
+
+// A folder when it has items, and a request otherwise
+type item struct {
+	Name     string            `json:"name"`
+	Item     []item            `json:"item"`
+	Request  *request          `json:"request"`
+	Auth     *auth             `json:"auth"`
+	Event    []event           `json:"event"`
+	Response []json.RawMessage `json:"response"`
+}
+
+type request struct {
+	Method string     `json:"method"`
+	Header []keyValue `json:"header"`
+	URL    url        `json:"url"`
+	Body   *body      `json:"body"`
+	Auth   *auth      `json:"auth"`
+}
+
+// URLs are written as strings or as objects with the string in raw
+type url struct {
+	Raw string `json:"raw"`
+}
+
+func (u *url) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &u.Raw); err == nil {
+		return nil
+	}
+	var object struct {
+		Raw string `json:"raw"`
+	}
+	err := json.Unmarshal(data, &object)
+	u.Raw = object.Raw
+	return err
+}
+
+type keyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled"`
+	Type     string      `json:"type"`
+	Src      interface{} `json:"src"`
+}
+
+// Returns the value as text; values of variables may be numbers or booleans
+func (kv keyValue) text() string {
+	switch v := kv.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	encoded, _ := json.Marshal(kv.Value)
+	return string(encoded)
+}
+
+type body struct {
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw"`
+	URLEncoded []keyValue `json:"urlencoded"`
+	FormData   []keyValue `json:"formdata"`
+	File       struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+type auth struct {
+	Type   string     `json:"type"`
+	Bearer []keyValue `json:"bearer"`
+	Basic  []keyValue `json:"basic"`
+	APIKey []keyValue `json:"apikey"`
+}
+
+// Returns the value of an attribute of the auth
+func (a *auth) param(params []keyValue, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.text()
+		}
+	}
+	return ""
+}
+
+type event struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec json.RawMessage `json:"exec"`
+	} `json:"script"`
+}
+
+// A Postman environment
+type Environment struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Key     string      `json:"key"`
+		Value   interface{} `json:"value"`
+		Enabled *bool       `json:"enabled"`
+		Type    string      `json:"type"`
+	} `json:"values"`
+}
+
+// A request file converted from a collection
+type File struct {
+	// name of the file, without a directory
+	Name string
+	Text string
+}
+
+// Variables of an environment converted from Postman
+type Variables struct {
+	Name   string
+	Values map[string]string
+
+	// values that Postman keeps as secret
+	Secrets map[string]string
+}
+
+// Read a Postman collection or environment
+// Returns either a collection or an environment
+func Load(file string) (*Collection, *Environment, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var kind struct {
+		Info   *json.RawMessage `json:"info"`
+		Values *json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return nil, nil, fmt.Errorf("invalid Postman file %s: %w", file, err)
+	}
+	switch {
+	case kind.Info != nil:
+		var c Collection
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, nil, fmt.Errorf("invalid Postman collection %s: %w", file, err)
+		}
+		if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "/v2.1") {
+			return nil, nil, errors.New("only Postman v2.1 collections are supported: " + file)
+		}
+		return &c, nil, nil
+	case kind.Values != nil:
+		var env Environment
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, nil, fmt.Errorf("invalid Postman environment %s: %w", file, err)
+		}
+		return nil, &env, nil
+	}
+	return nil, nil, errors.New("not a Postman collection or environment: " + file)
+}
+
+// Convert collections into request files and environments into variables
+// Collection variables become file variables, except those that an environment also defines;
+// these are added to the environments that lack them, so that environments override them as in Postman
+// Returns what could not be converted, one line per item
+func Convert(collections []*Collection, environments []*Environment) ([]File, []Variables, []string) {
+	var warnings []string
+
+	var converted []Variables
+	overridden := map[string]bool{}
+	for _, env := range environments {
+		vars := Variables{Name: env.Name, Values: map[string]string{}, Secrets: map[string]string{}}
+		for _, v := range env.Values {
+			if v.Enabled != nil && !*v.Enabled {
+				continue
+			}
+			value := keyValue{Value: v.Value}.text()
+			if v.Type == "secret" {
+				vars.Secrets[v.Key] = value
+			} else {
+				vars.Values[v.Key] = value
+			}
+			overridden[v.Key] = true
+		}
+		converted = append(converted, vars)
+	}
+
+	var files []File
+	names := map[string]bool{}
+	for _, c := range collections {
+		var fileVars []string
+		for _, v := range c.Variable {
+			if v.Disabled {
+				continue
+			}
+			if !overridden[v.Key] {
+				fileVars = append(fileVars, "@"+v.Key+" = "+v.text())
+				continue
+			}
+			for _, vars := range converted {
+				_, isValue := vars.Values[v.Key]
+				_, isSecret := vars.Secrets[v.Key]
+				if !isValue && !isSecret {
+					vars.Values[v.Key] = v.text()
+				}
+			}
+		}
+
+		conv := converter{warnings: &warnings}
+		conv.scripts(c.Info.Name, c.Event)
+		conv.folder(c.Info.Name, nil, c.Item, c.Auth)
+
+		for _, f := range conv.files {
+			if len(f.blocks) == 0 {
+				continue
+			}
+			name := fileName(f.name)
+			for i := 2; names[name]; i++ {
+				name = fileName(fmt.Sprintf("%s (%d)", f.name, i))
+			}
+			names[name] = true
+
+			text := strings.Join(f.blocks, "\n###\n")
+			if len(fileVars) > 0 {
+				text = strings.Join(fileVars, "\n") + "\n\n###\n" + text
+			}
+			files = append(files, File{Name: name, Text: text})
+		}
+	}
+	return files, converted, warnings
+}
+
+// Returns a file name for a collection or folder
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		name = "requests"
+	}
+	return name + ".http"
+}
+
+// Requests of a folder, written to a file of their own
+type folderFile struct {
+	name   string
+	blocks []string
+	slugs  map[string]bool
+}
+
+// Converts the items of a collection
+type converter struct {
+	files    []*folderFile
+	warnings *[]string
+}
+
+// Report something that could not be converted
+func (c *converter) warn(path string, format string, a ...interface{}) {
+	*c.warnings = append(*c.warnings, path+": "+fmt.Sprintf(format, a...))
+}
+
+// Report the scripts of an item; they cannot run in hitman
+func (c *converter) scripts(path string, events []event) {
+	for _, e := range events {
+		exec := strings.TrimSpace(string(e.Script.Exec))
+		if exec == "" || exec == "[]" || exec == `""` || exec == `[""]` {
+			continue
+		}
+		switch e.Listen {
+		case "prerequest":
+			c.warn(path, "pre-request script ignored")
+		case "test":
+			c.warn(path, "test script ignored")
+		default:
+			c.warn(path, "%s script ignored", e.Listen)
+		}
+	}
+}
+
+// Convert the requests of a folder into a file and its subfolders into files of their own
+// Requests without auth of their own use the auth of the closest folder that has one
+func (c *converter) folder(path string, names []string, items []item, inherited *auth) {
+	file := &folderFile{name: strings.Join(names, " - "), slugs: map[string]bool{}}
+	if file.name == "" {
+		file.name = path
+	}
+	c.files = append(c.files, file)
+
+	for _, it := range items {
+		itemPath := path + "/" + it.Name
+		c.scripts(itemPath, it.Event)
+
+		if it.Request == nil {
+			folderAuth := inherited
+			if it.Auth != nil && it.Auth.Type != "inherit" {
+				folderAuth = it.Auth
+			}
+			c.folder(itemPath, append(append([]string{}, names...), it.Name), it.Item, folderAuth)
+			continue
+		}
+
+		if len(it.Response) > 0 {
+			c.warn(itemPath, "saved responses ignored")
+		}
+		requestAuth := inherited
+		if it.Request.Auth != nil && it.Request.Auth.Type != "inherit" {
+			requestAuth = it.Request.Auth
+		}
+		file.blocks = append(file.blocks, c.request(itemPath, it.Name, it.Request, requestAuth, file.slugs))
+	}
+}
+
+var (
+	// a {{$name}} dynamic variable of Postman
+	dynamicVariable = regexp.MustCompile(`{{\s*(\$[A-Za-z]+)\s*}}`)
+
+	// a {{template}}, which may have quotes and spaces in hitman's syntax
+	template = regexp.MustCompile(`{{.*?}}`)
+
+	// characters left out of request names
+	nameChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Dynamic variables of Postman and the built-in functions that replace them
+var dynamicVariables = map[string]string{
+	"$guid":         "{{$uuid}}",
+	"$randomUUID":   "{{$uuid}}",
+	"$timestamp":    "{{$timestamp}}",
+	"$isoTimestamp": "{{$isoTimestamp}}",
+	"$randomInt":    "{{$randomInt 0 1000}}",
+}
+
+// Replace dynamic variables with the built-in functions of hitman
+func (c *converter) dynamic(path string, text string) string {
+	return dynamicVariable.ReplaceAllStringFunc(text, func(match string) string {
+		name := dynamicVariable.FindStringSubmatch(match)[1]
+		if replacement, prs := dynamicVariables[name]; prs {
+			return replacement
+		}
+		c.warn(path, "dynamic variable %s not supported", name)
+		return match
+	})
+}
+
+// Write a request
+// Requests with a raw body are written in the .http format and others in hitman's syntax,
+// which quotes values that have spaces
+func (c *converter) request(path string, name string, r *request, a *auth, slugs map[string]bool) string {
+	var b *body
+	if r.Body != nil && !r.Body.Disabled && r.Body.Mode != "" {
+		b = r.Body
+	}
+	raw := b != nil && (b.Mode == "raw" || b.Mode == "graphql")
+
+	value := func(s string) string {
+		s = c.dynamic(path, s)
+		plain := template.ReplaceAllString(s, "x")
+		if !raw && plain != "" && (strings.ContainsAny(plain, " :") || plain[0] == '#') && !strings.Contains(plain, `"`) {
+			return `"` + s + `"`
+		}
+		return s
+	}
+
+	var lines []string
+	lines = append(lines, "# "+name)
+	slug := strings.Trim(nameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug != "" {
+		for i, base := 2, slug; slugs[slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		slugs[slug] = true
+		lines = append(lines, "# @name "+slug)
+	}
+
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+	target := r.URL.Raw
+
+	var headers []string
+	hasHeader := map[string]bool{}
+	for _, h := range r.Header {
+		if h.Disabled {
+			headers = append(headers, "# "+h.Key+": "+h.text())
+			continue
+		}
+		hasHeader[strings.ToLower(h.Key)] = true
+		headers = append(headers, h.Key+": "+value(h.text()))
+	}
+
+	// auth is sent as headers, which both formats support, and Basic auth with -auth in hitman's syntax
+	var flags []string
+	if a != nil && !hasHeader["authorization"] {
+		switch a.Type {
+		case "noauth":
+		case "bearer":
+			headers = append(headers, "Authorization: "+value("Bearer "+a.param(a.Bearer, "token")))
+		case "basic":
+			credentials := a.param(a.Basic, "username") + ":" + a.param(a.Basic, "password")
+			switch {
+			case strings.Contains(credentials, `"`):
+				c.warn(path, "basic auth credentials with quotes not supported")
+			case !raw:
+				flags = append(flags, "-auth "+value(credentials))
+			case strings.Contains(credentials, "{{"):
+				c.warn(path, "basic auth credentials with variables not supported with a raw body")
+			default:
+				headers = append(headers, "Authorization: "+value(`Basic {{$base64 "`+credentials+`"}}`))
+			}
+		case "apikey":
+			key, v := a.param(a.APIKey, "key"), a.param(a.APIKey, "value")
+			if a.param(a.APIKey, "in") == "query" {
+				sep := "?"
+				if strings.Contains(target, "?") {
+					sep = "&"
+				}
+				target += sep + key + "=" + v
+			} else {
+				headers = append(headers, key+": "+value(v))
+			}
+		default:
+			c.warn(path, "%s auth not supported", a.Type)
+		}
+	}
+
+	if raw {
+		lines = append(lines, method+" "+c.dynamic(path, target))
+	} else {
+		lines = append(lines, method+" "+`"`+c.dynamic(path, target)+`"`)
+	}
+
+	var bodyLines []string
+	if b != nil {
+		switch b.Mode {
+		case "raw":
+			if b.Options.Raw.Language == "json" && !hasHeader["content-type"] {
+				headers = append(headers, "Content-Type: application/json")
+			}
+			bodyLines = append(bodyLines, "", c.dynamic(path, strings.TrimSpace(b.Raw)))
+		case "graphql":
+			graphQL := map[string]interface{}{"query": b.GraphQL.Query}
+			var variables interface{}
+			if err := json.Unmarshal([]byte(b.GraphQL.Variables), &variables); err == nil && variables != nil {
+				graphQL["variables"] = variables
+			}
+			encoded, _ := json.MarshalIndent(graphQL, "", "  ")
+			if !hasHeader["content-type"] {
+				headers = append(headers, "Content-Type: application/json")
+			}
+			bodyLines = append(bodyLines, "", string(encoded))
+		case "urlencoded":
+			if !hasHeader["content-type"] {
+				headers = append(headers, "Content-Type: application/x-www-form-urlencoded")
+			}
+			for _, field := range b.URLEncoded {
+				line := field.Key + "=" + value(field.text())
+				if field.Disabled {
+					line = "# " + line
+				}
+				bodyLines = append(bodyLines, line)
+			}
+		case "formdata":
+			for _, field := range b.FormData {
+				line := field.Key + "=" + value(field.text())
+				if field.Type == "file" {
+					src := sources(field.Src)
+					if len(src) != 1 {
+						c.warn(path, "form field %s must have exactly one file", field.Key)
+						continue
+					}
+					line = field.Key + " < " + value(src[0])
+				}
+				if field.Disabled {
+					line = "# " + line
+				}
+				bodyLines = append(bodyLines, line)
+			}
+			bodyLines = append(bodyLines, "-multipart")
+		case "file":
+			bodyLines = append(bodyLines, "< "+value(b.File.Src))
+		default:
+			c.warn(path, "%s body not supported", b.Mode)
+		}
+	}
+
+	lines = append(lines, headers...)
+	lines = append(lines, bodyLines...)
+	lines = append(lines, flags...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Returns the files of a form field; Postman writes one file as a string and several as a list
+func sources(src interface{}) []string {
+	switch s := src.(type) {
+	case string:
+		return []string{s}
+	case []interface{}:
+		var files []string
+		for _, f := range s {
+			if f, ok := f.(string); ok {
+				files = append(files, f)
+			}
+		}
+		return files
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package postman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ramitmittal/hitman/internal/parser"
+)
+
+const collection = `{
+  "info": {"name": "Shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "https://shop.example.com"},
+    {"key": "token", "value": "collection-token"}
+  ],
+  "item": [
+    {
+      "name": "Health",
+      "request": {"method": "GET", "url": "{{baseUrl}}/health", "auth": {"type": "noauth"}}
+    },
+    {
+      "name": "Orders",
+      "event": [{"listen": "prerequest", "script": {"exec": ["pm.environment.set('x', 1)"]}}],
+      "item": [
+        {
+          "name": "Create order",
+          "event": [{"listen": "test", "script": {"exec": ["pm.test('ok')"]}}],
+          "response": [{"name": "created"}],
+          "request": {
+            "method": "POST",
+            "header": [
+              {"key": "X-Request-Id", "value": "{{$guid}}"},
+              {"key": "X-Debug", "value": "true", "disabled": true}
+            ],
+            "url": {"raw": "{{baseUrl}}/orders?draft=true", "host": ["{{baseUrl}}"]},
+            "body": {"mode": "raw", "raw": "{\n  \"sku\": \"A-1\"\n}", "options": {"raw": {"language": "json"}}}
+          }
+        },
+        {
+          "name": "Login",
+          "request": {
+            "method": "POST",
+            "auth": {"type": "basic", "basic": [{"key": "username", "value": "{{username}}"}, {"key": "password", "value": "{{password}}"}]},
+            "url": "{{baseUrl}}/login",
+            "body": {"mode": "urlencoded", "urlencoded": [{"key": "scope", "value": "read write"}, {"key": "debug", "value": "1", "disabled": true}]}
+          }
+        },
+        {
+          "name": "Upload",
+          "request": {
+            "method": "PUT",
+            "auth": {"type": "awsv4"},
+            "url": "{{baseUrl}}/upload",
+            "body": {"mode": "formdata", "formdata": [{"key": "title", "value": "photo", "type": "text"}, {"key": "file", "src": "./photo.jpg", "type": "file"}]}
+          }
+        },
+        {
+          "name": "Refresh",
+          "request": {
+            "method": "POST",
+            "auth": {"type": "basic", "basic": [{"key": "username", "value": "alice"}, {"key": "password", "value": "s3cret"}]},
+            "url": "{{baseUrl}}/refresh",
+            "body": {"mode": "raw", "raw": "grant_type=refresh_token"}
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+const environment = `{
+  "name": "staging",
+  "values": [
+    {"key": "baseUrl", "value": "https://staging.example.com", "enabled": true},
+    {"key": "apiKey", "value": "hidden", "type": "secret", "enabled": true},
+    {"key": "unused", "value": "x", "enabled": false}
+  ]
+}`
+
+func load(t *testing.T, text string) (*Collection, *Environment) {
+	file := filepath.Join(t.TempDir(), "postman.json")
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		panic(err)
+	}
+	c, env, err := Load(file)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	return c, env
+}
+
+func TestConvert(t *testing.T) {
+	c, _ := load(t, collection)
+	_, env := load(t, environment)
+	if c == nil || env == nil {
+		t.FailNow()
+	}
+
+	files, environments, warnings := Convert([]*Collection{c}, []*Environment{env})
+	if len(files) != 2 || files[0].Name != "Shop.http" || files[1].Name != "Orders.http" {
+		t.Log(files)
+		t.FailNow()
+	}
+
+	// baseUrl is defined by the environment, so only token is a file variable
+	if !strings.HasPrefix(files[1].Text, "@token = collection-token\n\n###\n") {
+		t.Log(files[1].Text)
+		t.Fail()
+	}
+	if len(environments) != 1 || environments[0].Values["baseUrl"] != "https://staging.example.com" || environments[0].Secrets["apiKey"] != "hidden" {
+		t.Log(environments)
+		t.Fail()
+	}
+	if _, prs := environments[0].Values["unused"]; prs {
+		t.Fail()
+	}
+
+	var tests = []struct {
+		file    int
+		block   int
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+	}{
+		{0, 1, "health", "GET", "{{baseUrl}}/health", map[string]string{}},
+		{1, 1, "create-order", "POST", "{{baseUrl}}/orders?draft=true", map[string]string{
+			"Authorization": "Bearer {{token}}",
+			"X-Request-Id":  "{{$uuid}}",
+			"Content-Type":  "application/json",
+		}},
+		{1, 2, "login", "POST", "{{baseUrl}}/login", map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		}},
+		{1, 3, "upload", "PUT", "{{baseUrl}}/upload", map[string]string{}},
+		{1, 4, "refresh", "POST", "{{baseUrl}}/refresh", map[string]string{
+			"Authorization": `Basic {{$base64 "alice:s3cret"}}`,
+		}},
+	}
+
+	for _, test := range tests {
+		blocks := parser.SplitBlocks(files[test.file].Text)
+		block := blocks[test.block]
+		if block.Name != test.name {
+			t.Log(block.Name)
+			t.Fail()
+		}
+		result, err := parser.ParseRequest(block.Text)
+		if err != nil {
+			t.Log(block.Text, err)
+			t.Fail()
+			continue
+		}
+		if result.Method != test.method || result.Url != test.url {
+			t.Log(test.name, result.Method, result.Url)
+			t.Fail()
+		}
+		for name, value := range test.headers {
+			if result.Headers[name] != value {
+				t.Log(test.name, result.Headers)
+				t.Fail()
+			}
+		}
+		if _, prs := result.Headers["X-Debug"]; prs {
+			t.Fail()
+		}
+
+		switch test.name {
+		case "create-order":
+			if result.Body.Text != "{\n  \"sku\": \"A-1\"\n}" {
+				t.Log(result.Body.Text)
+				t.Fail()
+			}
+		case "login":
+			if len(result.Items) != 1 || result.Items[0].Value != "read write" {
+				t.Log(result.Items)
+				t.Fail()
+			}
+			// variables in credentials cannot be written inside {{$base64}}
+			if _, prs := result.Headers["Authorization"]; prs || result.Flags["auth"] != "{{username}}:{{password}}" {
+				t.Log(result.Headers, result.Flags)
+				t.Fail()
+			}
+		case "refresh":
+			if result.Body.Text != "grant_type=refresh_token" || len(result.Items) != 0 {
+				t.Log(result.Body, result.Items)
+				t.Fail()
+			}
+		case "upload":
+			if len(result.Items) != 2 || result.Items[1].Op != "<" || result.Items[1].Value != "./photo.jpg" {
+				t.Log(result.Items)
+				t.Fail()
+			}
+			if _, prs := result.Flags["multipart"]; !prs {
+				t.Fail()
+			}
+		}
+	}
+
+	expected := []string{
+		"Shop/Orders: pre-request script ignored",
+		"Shop/Orders/Create order: test script ignored",
+		"Shop/Orders/Create order: saved responses ignored",
+		"Shop/Orders/Upload: awsv4 auth not supported",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Log(warnings)
+		t.Fail()
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "old.json")
+	if err := os.WriteFile(file, []byte(`{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.0.0/collection.json"}}`), 0644); err != nil {
+		panic(err)
+	}
+	if _, _, err := Load(file); err == nil {
+		t.Fail()
+	}
+
+	if err := os.WriteFile(file, []byte(`{"name": "not postman"}`), 0644); err != nil {
+		panic(err)
+	}
+	if _, _, err := Load(file); err == nil {
+		t.Fail()
+	}
+}
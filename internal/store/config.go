@@ -0,0 +1,48 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// User preferences read from config.json in the config directory
+type Config struct {
+	// scheme for URLs of remote hosts without one; https when empty
+	DefaultScheme string `json:"defaultScheme"`
+
+	// environment used by requests without -env
+	Environment string `json:"environment"`
+
+	// headers hidden in the viewport and clipboard and kept out of the saved input besides Authorization and Cookie
+	RedactHeaders []string `json:"redactHeaders"`
+
+	// number of backups of the input to keep; 3 when not set
+	Backups *int `json:"backups"`
+}
+
+// Returns the contents of config.json in the config directory
+// Returns an empty config when the file does not exist
+func LoadConfig() (Config, error) {
+	var config Config
+
+	file := ConfigPath("config.json")
+	bytes, err := ioutil.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return config, errors.New("invalid config " + file + ": " + err.Error())
+	}
+	if config.DefaultScheme != "" && config.DefaultScheme != "http" && config.DefaultScheme != "https" {
+		return config, errors.New("invalid config " + file + ": defaultScheme must be http or https")
+	}
+	if config.Backups != nil && *config.Backups < 0 {
+		return config, errors.New("invalid config " + file + ": backups must not be negative")
+	}
+	return config, nil
+}
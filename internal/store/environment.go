@@ -0,0 +1,151 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// A named set of variables, e.g. for staging or production
+type Environment struct {
+	Variables map[string]string
+
+	// how requests in the environment get an access token; nil when they do not
+	OAuth2 *OAuth2Config
+
+	// how requests sent with -hmac are signed; nil when they cannot be
+	HMAC *HMACConfig
+}
+
+// Settings for fetching OAuth2 access tokens, written under "oauth2" in an environment
+// Values may contain {{variables}}
+type OAuth2Config struct {
+	// client_credentials, password or refresh_token
+	GrantType    string `json:"grantType"`
+	TokenURL     string `json:"tokenUrl"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	Scope        string `json:"scope"`
+
+	// for the password grant
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// for the refresh_token grant
+	RefreshToken string `json:"refreshToken"`
+
+	// send client credentials in the form body instead of a Basic Authorization header
+	ClientAuthInBody bool `json:"clientAuthInBody"`
+}
+
+// Settings for signing requests with an HMAC, written under "hmac" in an environment
+// Values other than Format may contain {{variables}}
+type HMACConfig struct {
+	// sha1, sha256 or sha512; sha256 when empty
+	Algorithm string `json:"algorithm"`
+	Secret    string `json:"secret"`
+
+	// what is signed, in order: timestamp, method, path, body, header:Name or literal:text
+	Parts []string `json:"parts"`
+
+	// joins the parts; "." when empty
+	Separator *string `json:"separator"`
+
+	// hex or base64; hex when empty
+	Encoding string `json:"encoding"`
+
+	// header that carries the signature
+	Header string `json:"header"`
+
+	// value of the header with {signature} and {timestamp} replaced; "{signature}" when empty
+	Format string `json:"format"`
+
+	// header that carries the timestamp, if the receiver expects one
+	TimestampHeader string `json:"timestampHeader"`
+}
+
+func (e *Environment) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	e.Variables = make(map[string]string, len(fields))
+	for name, raw := range fields {
+		if name == "oauth2" {
+			e.OAuth2 = &OAuth2Config{}
+			if err := json.Unmarshal(raw, e.OAuth2); err != nil {
+				return fmt.Errorf("oauth2: %w", err)
+			}
+			continue
+		}
+		if name == "hmac" {
+			e.HMAC = &HMACConfig{}
+			if err := json.Unmarshal(raw, e.HMAC); err != nil {
+				return fmt.Errorf("hmac: %w", err)
+			}
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("variable %s must be a string", name)
+		}
+		e.Variables[name] = value
+	}
+	return nil
+}
+
+// Returns the path of the file that defines environments
+func environmentFile() string {
+	return ConfigPath("environments.json")
+}
+
+// Returns the environments defined in environments.json in the config directory keyed by name
+// Environments in the extra files are merged over them in order; their variables replace variables of the same name
+// Returns no environments when none of the files exist
+func LoadEnvironments(extra ...string) (map[string]Environment, error) {
+	environments := map[string]Environment{}
+
+	for _, file := range append([]string{environmentFile()}, extra...) {
+		bytes, err := ioutil.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var loaded map[string]Environment
+		if err := json.Unmarshal(bytes, &loaded); err != nil {
+			return nil, errors.New("invalid environments " + file + ": " + err.Error())
+		}
+		for name, env := range loaded {
+			environments[name] = mergeEnvironment(environments[name], env)
+		}
+	}
+	return environments, nil
+}
+
+// Returns the environment with the variables and settings of override replacing its own
+func mergeEnvironment(env Environment, override Environment) Environment {
+	merged := Environment{
+		Variables: make(map[string]string, len(env.Variables)+len(override.Variables)),
+		OAuth2:    env.OAuth2,
+		HMAC:      env.HMAC,
+	}
+	for k, v := range env.Variables {
+		merged.Variables[k] = v
+	}
+	for k, v := range override.Variables {
+		merged.Variables[k] = v
+	}
+	if override.OAuth2 != nil {
+		merged.OAuth2 = override.OAuth2
+	}
+	if override.HMAC != nil {
+		merged.HMAC = override.HMAC
+	}
+	return merged
+}
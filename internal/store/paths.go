@@ -0,0 +1,145 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Name of the directories that hitman keeps its files in
+const appName = "hitman"
+
+// directory set by SetConfigDir
+var configDirOverride string
+
+// Returns the user's home directory
+func homeDir() string {
+	var home string
+
+	if runtime.GOOS == "windows" {
+		home = os.Getenv("HOMEDRIVE") + os.Getenv("HOMEPATH")
+		if home == "" {
+			home = os.Getenv("USERPROFILE")
+		}
+	} else {
+		home = os.Getenv("HOME")
+	}
+	return home
+}
+
+// Returns the directory in an XDG base directory variable, or fallback when it is not set
+// Relative paths are ignored as the specification requires
+func xdgDir(variable string, fallback string) string {
+	if dir := os.Getenv(variable); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(fallback, appName)
+}
+
+// Use dir for config files instead of the default directory
+func SetConfigDir(dir string) {
+	configDirOverride = dir
+}
+
+// Returns the directory for files that the user edits, like the config and environments
+// $XDG_CONFIG_HOME/hitman, or ~/.config/hitman when it is not set
+func ConfigDir() string {
+	if configDirOverride != "" {
+		return configDirOverride
+	}
+	if runtime.GOOS == "windows" && os.Getenv("XDG_CONFIG_HOME") == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, appName)
+		}
+	}
+	return xdgDir("XDG_CONFIG_HOME", filepath.Join(homeDir(), ".config"))
+}
+
+// Returns the directory for files that hitman writes on its own, like the saved input and sessions
+// $XDG_STATE_HOME/hitman, or ~/.local/state/hitman when it is not set
+func StateDir() string {
+	if runtime.GOOS == "windows" && os.Getenv("XDG_STATE_HOME") == "" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, appName)
+		}
+	}
+	return xdgDir("XDG_STATE_HOME", filepath.Join(homeDir(), ".local", "state"))
+}
+
+// Returns the directory for files that the user keeps, like collections of requests
+// $XDG_DATA_HOME/hitman, or ~/.local/share/hitman when it is not set
+func DataDir() string {
+	if runtime.GOOS == "windows" && os.Getenv("XDG_DATA_HOME") == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, appName)
+		}
+	}
+	return xdgDir("XDG_DATA_HOME", filepath.Join(homeDir(), ".local", "share"))
+}
+
+// Returns the path of a file in the config directory
+func ConfigPath(name string) string {
+	return filepath.Join(ConfigDir(), name)
+}
+
+// Returns the path of a file in the data directory
+func DataPath(name string) string {
+	return filepath.Join(DataDir(), name)
+}
+
+// Returns the path of a file in the state directory
+func StatePath(name string) string {
+	return filepath.Join(StateDir(), name)
+}
+
+// Move the input that older versions saved in $HOME/.hitman to the state directory
+// Does nothing when the input was saved in the state directory already or $HOME/.hitman is not a file,
+// e.g. when it is the .hitman directory of a project in the home directory
+func MigrateInput() error {
+	legacy := filepath.Join(homeDir(), ".hitman")
+	file := TextPath()
+
+	if _, err := os.Lstat(file); !os.IsNotExist(err) {
+		return nil
+	}
+	if info, err := os.Lstat(legacy); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.Rename(legacy, file)
+}
+
+// Write a file, creating the directories that it is in
+// The data is written to a temporary file that replaces the file, so a crash never leaves it half written
+func writeFile(file string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(file)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	if dir := ConfigDir(); dir != filepath.Join(home, ".config", "hitman") {
+		t.Log(dir)
+		t.Fail()
+	}
+	if dir := StateDir(); dir != filepath.Join(home, ".local", "state", "hitman") {
+		t.Log(dir)
+		t.Fail()
+	}
+	if dir := DataDir(); dir != filepath.Join(home, ".local", "share", "hitman") {
+		t.Log(dir)
+		t.Fail()
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("XDG_STATE_HOME", "relative/state")
+	if dir := ConfigDir(); dir != filepath.Join(xdg, "hitman") {
+		t.Log(dir)
+		t.Fail()
+	}
+	// relative paths are ignored
+	if dir := StateDir(); dir != filepath.Join(home, ".local", "state", "hitman") {
+		t.Log(dir)
+		t.Fail()
+	}
+
+	SetConfigDir(home)
+	defer SetConfigDir("")
+	if file := ConfigPath("config.json"); file != filepath.Join(home, "config.json") {
+		t.Log(file)
+		t.Fail()
+	}
+}
+
+func TestMigrateInput(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	legacy := filepath.Join(home, ".hitman")
+
+	// nothing to move
+	if err := MigrateInput(); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+
+	if err := os.WriteFile(legacy, []byte("GET old.example.com"), 0644); err != nil {
+		panic(err)
+	}
+	if err := MigrateInput(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if readFile(t, TextPath()) != "GET old.example.com" {
+		t.Fail()
+	}
+	if _, err := os.Lstat(legacy); !os.IsNotExist(err) {
+		t.Fail()
+	}
+
+	// an input saved by this version is never replaced
+	if err := os.WriteFile(legacy, []byte("GET older.example.com"), 0644); err != nil {
+		panic(err)
+	}
+	if err := MigrateInput(); err != nil {
+		t.FailNow()
+	}
+	if readFile(t, TextPath()) != "GET old.example.com" || readFile(t, legacy) != "GET older.example.com" {
+		t.Fail()
+	}
+
+	// the .hitman directory of a project is not the input
+	setHome(t, t.TempDir())
+	if err := os.Mkdir(filepath.Join(os.Getenv("HOME"), ".hitman"), 0755); err != nil {
+		panic(err)
+	}
+	if err := MigrateInput(); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	if _, err := os.Lstat(TextPath()); !os.IsNotExist(err) {
+		t.Fail()
+	}
+}
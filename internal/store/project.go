@@ -0,0 +1,94 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Name of the directory that marks the root of a project and holds its request files
+const ProjectDirName = ".hitman"
+
+// Returns the root of the project that dir belongs to and the directory that holds its request files
+// The root is the closest of dir and its parents that has a .hitman directory or request files
+// The search stops below the home directory, which keeps hitman's own files
+func FindProject(dir string) (root string, requests string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	home := filepath.Clean(homeDir())
+
+	for {
+		if home != "." && dir == home {
+			return "", "", false
+		}
+		if info, err := os.Stat(filepath.Join(dir, ProjectDirName)); err == nil && info.IsDir() {
+			return dir, filepath.Join(dir, ProjectDirName), true
+		}
+		if names, err := ListRequestFiles(dir); err == nil && len(names) > 0 {
+			return dir, dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// Returns the files that define environments for a project, in the order that they override each other
+func ProjectEnvironmentFiles(root string) []string {
+	return []string{
+		filepath.Join(root, ProjectDirName, "environments.json"),
+		filepath.Join(root, "http-client.env.json"),
+		filepath.Join(root, "http-client.private.env.json"),
+	}
+}
+
+// Add the variables of an environment to the environment files of a project
+// Secrets are kept in http-client.private.env.json, which is meant to be left out of version control
+func SaveProjectEnvironment(root string, name string, variables map[string]string, secrets map[string]string) error {
+	files := ProjectEnvironmentFiles(root)
+	if err := mergeEnvironmentFile(files[1], name, variables, 0644); err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	return mergeEnvironmentFile(files[2], name, secrets, 0600)
+}
+
+// Add variables to an environment in a file, keeping everything else in the file as it is
+func mergeEnvironmentFile(file string, name string, variables map[string]string, perm os.FileMode) error {
+	environments := map[string]map[string]json.RawMessage{}
+	if bytes, err := ioutil.ReadFile(file); err == nil {
+		if err := json.Unmarshal(bytes, &environments); err != nil {
+			return errors.New("invalid environments " + file + ": " + err.Error())
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	env := environments[name]
+	if env == nil {
+		env = map[string]json.RawMessage{}
+	}
+	for k, v := range variables {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		env[k] = encoded
+	}
+	environments[name] = env
+
+	bytes, err := json.MarshalIndent(environments, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(file, append(bytes, '\n'), perm)
+}
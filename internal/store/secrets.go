@@ -0,0 +1,208 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Environment variable that holds the passphrase for secrets
+// Secrets are encrypted with a key file when it is not set
+const PassphraseEnv = "HITMAN_PASSPHRASE"
+
+const (
+	kdfKeyFile = "keyfile"
+	kdfPBKDF2  = "pbkdf2-sha256"
+
+	pbkdf2Iterations = 310000
+)
+
+// The encrypted secrets file
+type secretsFile struct {
+	// how the key is obtained; keyfile or pbkdf2-sha256
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+
+	// AES-256-GCM nonce and sealed JSON object of names to values
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func secretsPath() string {
+	return ConfigPath("secrets.json")
+}
+
+// Returns the path of the key file that secrets are encrypted with when no passphrase is set
+func SecretKeyPath() string {
+	return ConfigPath("secrets.key")
+}
+
+// serializes reads and writes of the secrets file
+var secretsMu sync.Mutex
+
+// keys derived from passphrases, as deriving one is slow on purpose
+var derivedKeys = map[string][]byte{}
+
+// Derive a key from a passphrase with PBKDF2-HMAC-SHA256 (RFC 8018)
+func pbkdf2Key(passphrase string, salt []byte, iterations int) []byte {
+	cacheKey := strconv.Itoa(iterations) + "\x00" + string(salt) + "\x00" + passphrase
+	if key, prs := derivedKeys[cacheKey]; prs {
+		return key
+	}
+
+	key := pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New)
+	derivedKeys[cacheKey] = key
+	return key
+}
+
+// Read the key file, creating it with a random key when create is true and it does not exist
+func readKeyFile(create bool) ([]byte, error) {
+	file := SecretKeyPath()
+	bytes, err := ioutil.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := writeFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	} else if err != nil {
+		return nil, errors.New("could not read secret key file: " + err.Error())
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(bytes)))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("invalid secret key file " + file)
+	}
+	return key, nil
+}
+
+// Returns the key that the secrets file is encrypted with
+func secretsKey(f *secretsFile, create bool) ([]byte, error) {
+	switch f.KDF {
+	case kdfKeyFile:
+		return readKeyFile(create)
+	case kdfPBKDF2:
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			return nil, errors.New("secrets are locked; set " + PassphraseEnv + " to unlock them")
+		}
+		return pbkdf2Key(passphrase, f.Salt, f.Iterations), nil
+	}
+	return nil, errors.New("unknown secrets encryption " + f.KDF)
+}
+
+func loadSecretsFile() (*secretsFile, error) {
+	bytes, err := ioutil.ReadFile(secretsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f secretsFile
+	if err := json.Unmarshal(bytes, &f); err != nil {
+		return nil, errors.New("corrupt secrets file: " + err.Error())
+	}
+	return &f, nil
+}
+
+// Returns the stored secrets keyed by name
+// Returns no secrets when none were saved
+func LoadSecrets() (map[string]string, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	f, err := loadSecretsFile()
+	if err != nil || f == nil {
+		return map[string]string{}, err
+	}
+	key, err := secretsKey(f, false)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, errors.New("could not decrypt secrets; the passphrase or key file is wrong")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, errors.New("corrupt secrets file: " + err.Error())
+	}
+	return secrets, nil
+}
+
+// Encrypt and save the secrets
+// A new secrets file uses the passphrase from HITMAN_PASSPHRASE if it is set, and a key file otherwise
+func SaveSecrets(secrets map[string]string) error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	f, err := loadSecretsFile()
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		f = &secretsFile{KDF: kdfKeyFile}
+		if os.Getenv(PassphraseEnv) != "" {
+			f.KDF = kdfPBKDF2
+			f.Salt = make([]byte, 16)
+			f.Iterations = pbkdf2Iterations
+			if _, err := rand.Read(f.Salt); err != nil {
+				return err
+			}
+		}
+	}
+	key, err := secretsKey(f, true)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+
+	bytes, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(secretsPath(), bytes, 0600)
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSecretsKeyFile(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+
+	if secrets, err := LoadSecrets(); err != nil || len(secrets) != 0 {
+		t.Log(secrets, err)
+		t.Fail()
+	}
+
+	if err := SaveSecrets(map[string]string{"token": "s3cret", "password": "hunter2"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	secrets, err := LoadSecrets()
+	if err != nil || secrets["token"] != "s3cret" || secrets["password"] != "hunter2" {
+		t.Log(secrets, err)
+		t.Fail()
+	}
+
+	// values are encrypted and files are private
+	if strings.Contains(readFile(t, secretsPath()), "s3cret") {
+		t.Fail()
+	}
+	for _, file := range []string{secretsPath(), SecretKeyPath()} {
+		if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+			t.Log(file, err)
+			t.Fail()
+		}
+	}
+
+	// a different key cannot decrypt them
+	if err := os.WriteFile(SecretKeyPath(), []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n"), 0600); err != nil {
+		panic(err)
+	}
+	if _, err := LoadSecrets(); err == nil {
+		t.Fail()
+	}
+
+	if err := os.WriteFile(SecretKeyPath(), []byte("not a key"), 0600); err != nil {
+		panic(err)
+	}
+	if _, err := LoadSecrets(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSecretsPassphrase(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	if err := SaveSecrets(map[string]string{"token": "s3cret"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if secrets, err := LoadSecrets(); err != nil || secrets["token"] != "s3cret" {
+		t.Log(secrets, err)
+		t.Fail()
+	}
+	if _, err := os.Stat(SecretKeyPath()); !os.IsNotExist(err) {
+		t.Fail()
+	}
+
+	// secrets are saved with the same passphrase
+	if err := SaveSecrets(map[string]string{"token": "changed"}); err != nil {
+		t.FailNow()
+	}
+	if secrets, err := LoadSecrets(); err != nil || secrets["token"] != "changed" {
+		t.Log(secrets, err)
+		t.Fail()
+	}
+
+	t.Setenv(PassphraseEnv, "wrong horse")
+	if _, err := LoadSecrets(); err == nil {
+		t.Fail()
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	if _, err := LoadSecrets(); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	var tests = []struct {
+		passphrase string
+		salt       string
+		iterations int
+		key        string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+
+	for _, test := range tests {
+		if key := hex.EncodeToString(pbkdf2Key(test.passphrase, []byte(test.salt), test.iterations)); key != test.key {
+			t.Log(test.iterations, key)
+			t.Fail()
+		}
+	}
+}
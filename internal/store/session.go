@@ -0,0 +1,304 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Name of the session used by requests that do not ask for one
+const DefaultSession = "default"
+
+// A cookie remembered by a session
+type Cookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"hostOnly,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+}
+
+func (c Cookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// Reports whether the cookie should be sent to u
+func (c Cookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if host != c.Domain && !strings.HasSuffix(host, "."+c.Domain) {
+		return false
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if p != c.Path {
+		if !strings.HasPrefix(p, c.Path) {
+			return false
+		}
+		if !strings.HasSuffix(c.Path, "/") && p[len(c.Path)] != '/' {
+			return false
+		}
+	}
+
+	return !c.Secure || u.Scheme == "https"
+}
+
+// Reports whether a domain is a public suffix, under which anyone can register a site, like com, co.uk or github.io
+// Single-label domains like localhost are public suffixes too
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// Session is an http.CookieJar that can be saved to disk and loaded by name
+type Session struct {
+	Name string
+
+	mu      sync.Mutex
+	cookies map[string]Cookie
+	dirty   bool
+}
+
+// Store cookies received from u
+func (s *Session) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+
+	for _, hc := range cookies {
+		c := Cookie{
+			Name:     hc.Name,
+			Value:    hc.Value,
+			Path:     hc.Path,
+			Secure:   hc.Secure,
+			HttpOnly: hc.HttpOnly,
+		}
+
+		if hc.Domain == "" {
+			c.Domain = host
+			c.HostOnly = true
+		} else {
+			c.Domain = strings.ToLower(strings.TrimPrefix(hc.Domain, "."))
+			if host != c.Domain && (net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+c.Domain)) {
+				// a server may only set cookies for its own domain
+				continue
+			}
+			if isPublicSuffix(c.Domain) {
+				// nor for every site under a public suffix like com or co.uk, as RFC 6265 requires
+				if host != c.Domain {
+					continue
+				}
+				c.HostOnly = true
+			}
+		}
+
+		if c.Path == "" || c.Path[0] != '/' {
+			c.Path = defaultCookiePath(u)
+		}
+
+		if hc.MaxAge < 0 {
+			c.Expires = now
+		} else if hc.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		} else if !hc.Expires.IsZero() {
+			c.Expires = hc.Expires
+		}
+
+		if c.expired(now) {
+			delete(s.cookies, c.key())
+		} else {
+			s.cookies[c.key()] = c
+		}
+		s.dirty = true
+	}
+}
+
+// Returns the cookies to send in a request to u
+func (s *Session) Cookies(u *url.URL) []*http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var matched []Cookie
+	for _, c := range s.cookies {
+		if !c.expired(now) && c.matches(u) {
+			matched = append(matched, c)
+		}
+	}
+
+	// longer paths first, as recommended by RFC 6265
+	sort.Slice(matched, func(i, j int) bool {
+		if len(matched[i].Path) != len(matched[j].Path) {
+			return len(matched[i].Path) > len(matched[j].Path)
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, c := range matched {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// Returns all unexpired cookies in the session sorted by domain, path and name
+func (s *Session) List() []Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	cookies := make([]Cookie, 0, len(s.cookies))
+	for _, c := range s.cookies {
+		if !c.expired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	sort.Slice(cookies, func(i, j int) bool {
+		return cookies[i].key() < cookies[j].key()
+	})
+	return cookies
+}
+
+// Remove a cookie from the session
+func (s *Session) Delete(c Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, prs := s.cookies[c.key()]; prs {
+		delete(s.cookies, c.key())
+		s.dirty = true
+	}
+}
+
+// The default path of a cookie is the directory of the request path
+func defaultCookiePath(u *url.URL) string {
+	p := u.EscapedPath()
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// Returns the directory that contains saved sessions
+func sessionDir() string {
+	return StatePath("sessions")
+}
+
+func sessionFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name[0] == '.' {
+		return "", errors.New("invalid session name: " + name)
+	}
+	return filepath.Join(sessionDir(), name+".json"), nil
+}
+
+// Returns the session saved with the provided name
+// Returns an empty session if it has not been saved before
+func LoadSession(name string) (*Session, error) {
+	file, err := sessionFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		Name:    name,
+		cookies: map[string]Cookie{},
+	}
+
+	bytes, err := ioutil.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cookies []Cookie
+	if err := json.Unmarshal(bytes, &cookies); err != nil {
+		return nil, errors.New("corrupt session " + name + ": " + err.Error())
+	}
+
+	now := time.Now()
+	for _, c := range cookies {
+		if !c.expired(now) {
+			s.cookies[c.key()] = c
+		}
+	}
+	return s, nil
+}
+
+// Save the session's cookies to disk
+// Does nothing if the session has not changed since it was loaded
+func SaveSession(s *Session) error {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.mu.Unlock()
+
+	if !dirty {
+		return nil
+	}
+
+	file, err := sessionFile(s.Name)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.MarshalIndent(s.List(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(file, bytes, 0600); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.dirty = false
+	s.mu.Unlock()
+	return nil
+}
+
+// Returns the names of all saved sessions
+func ListSessions() ([]string, error) {
+	entries, err := ioutil.ReadDir(sessionDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	return names, nil
+}
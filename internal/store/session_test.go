@@ -0,0 +1,171 @@
+package store
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParse(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// Returns the cookies that the session sends to a URL as "name=value" pairs
+func sent(s *Session, raw string) string {
+	var pairs []string
+	for _, c := range s.Cookies(mustParse(raw)) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+func TestSessionDomains(t *testing.T) {
+	setHome(t, t.TempDir())
+
+	var tests = []struct {
+		name   string
+		from   string
+		cookie *http.Cookie
+		to     map[string]string
+	}{
+		{"Host-only cookie", "http://example.com/", &http.Cookie{Name: "a", Value: "1"}, map[string]string{
+			"http://example.com/":     "a=1",
+			"http://api.example.com/": "",
+		}},
+		{"Domain cookie", "http://api.example.com/", &http.Cookie{Name: "a", Value: "1", Domain: ".example.com"}, map[string]string{
+			"http://example.com/":        "a=1",
+			"http://www.example.com/":    "a=1",
+			"http://notexample.com/":     "",
+			"http://example.com.evil.io": "",
+		}},
+		{"Another site's domain", "http://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "other.com"}, map[string]string{
+			"http://other.com/": "",
+		}},
+		{"Subdomain of the host", "http://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "api.example.com"}, map[string]string{
+			"http://api.example.com/": "",
+		}},
+		{"Public suffix", "http://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "com"}, map[string]string{
+			"http://example.com/": "",
+			"http://other.com/":   "",
+		}},
+		{"Multi-label public suffix", "http://a.example.co.uk/", &http.Cookie{Name: "a", Value: "1", Domain: "co.uk"}, map[string]string{
+			"http://a.example.co.uk/": "",
+			"http://bank.co.uk/":      "",
+		}},
+		{"Private public suffix", "https://me.github.io/", &http.Cookie{Name: "a", Value: "1", Domain: "github.io"}, map[string]string{
+			"https://you.github.io/": "",
+		}},
+		{"Single-label host", "http://localhost:8080/", &http.Cookie{Name: "a", Value: "1", Domain: "localhost"}, map[string]string{
+			"http://localhost:9090/":     "a=1",
+			"http://api.localhost:8080/": "",
+		}},
+		{"IP address", "http://127.0.0.1/", &http.Cookie{Name: "a", Value: "1", Domain: "0.0.1"}, map[string]string{
+			"http://127.0.0.1/": "",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := LoadSession("domains")
+			if err != nil {
+				panic(err)
+			}
+			s.SetCookies(mustParse(test.from), []*http.Cookie{test.cookie})
+			for to, expected := range test.to {
+				if cookies := sent(s, to); cookies != expected {
+					t.Log(to, cookies)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestSessionPaths(t *testing.T) {
+	setHome(t, t.TempDir())
+	s, err := LoadSession("paths")
+	if err != nil {
+		panic(err)
+	}
+
+	s.SetCookies(mustParse("http://example.com/api/users"), []*http.Cookie{
+		{Name: "default", Value: "1"},
+		{Name: "root", Value: "2", Path: "/"},
+		{Name: "docs", Value: "3", Path: "/docs/"},
+		{Name: "secure", Value: "4", Path: "/", Secure: true},
+	})
+
+	var tests = []struct {
+		url      string
+		expected string
+	}{
+		// longer paths come first
+		{"http://example.com/api", "default=1; root=2"},
+		{"http://example.com/api/users/1", "default=1; root=2"},
+		{"http://example.com/apis", "root=2"},
+		{"http://example.com/", "root=2"},
+		{"http://example.com/docs/intro", "docs=3; root=2"},
+		{"http://example.com/docs", "root=2"},
+		{"https://example.com/", "root=2; secure=4"},
+	}
+	for _, test := range tests {
+		if cookies := sent(s, test.url); cookies != test.expected {
+			t.Log(test.url, cookies)
+			t.Fail()
+		}
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	setHome(t, t.TempDir())
+	s, err := LoadSession("expiry")
+	if err != nil {
+		panic(err)
+	}
+	u := mustParse("http://example.com/")
+
+	s.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "max-age", Value: "2", MaxAge: 60},
+		{Name: "expires", Value: "3", Expires: time.Now().Add(time.Hour)},
+		{Name: "expired", Value: "4", Expires: time.Now().Add(-time.Hour)},
+	})
+	if cookies := sent(s, u.String()); cookies != "expires=3; max-age=2; session=1" {
+		t.Log(cookies)
+		t.Fail()
+	}
+
+	// a negative Max-Age removes a cookie
+	s.SetCookies(u, []*http.Cookie{{Name: "max-age", MaxAge: -1}})
+	if cookies := sent(s, u.String()); cookies != "expires=3; session=1" {
+		t.Log(cookies)
+		t.Fail()
+	}
+
+	// sessions are saved with their cookies
+	if err := SaveSession(s); err != nil {
+		t.FailNow()
+	}
+	loaded, err := LoadSession("expiry")
+	if err != nil {
+		t.FailNow()
+	}
+	if cookies := sent(loaded, u.String()); cookies != "expires=3; session=1" {
+		t.Log(cookies)
+		t.Fail()
+	}
+	if names, err := ListSessions(); err != nil || strings.Join(names, " ") != "expiry" {
+		t.Log(names, err)
+		t.Fail()
+	}
+
+	if _, err := LoadSession("../escape"); err == nil {
+		t.Fail()
+	}
+}
@@ -1,52 +1,75 @@
 package store
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
-	"path"
-	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/atotto/clipboard"
 )
 
-// Returns the contents of $HOME/.hitman
-// Returns placeholder text when an error is encountered
+// Returns the path of the file that stores the textarea's contents
+func TextPath() string {
+	return StatePath("input")
+}
+
+// how long the input is edited before its previous version is kept as another backup
+const backupInterval = 5 * time.Minute
+
+// Returns the path of the nth most recent backup of the input
+func backupPath(n int) string {
+	return TextPath() + "." + strconv.Itoa(n)
+}
+
+// Returns the contents of the saved input
+// Falls back to the most recent backup when the input cannot be read
+// Returns placeholder text when neither can be read
 func LoadText() string {
 	defaultText := "GET www.example.com"
 
-	var home string
-
-	if runtime.GOOS == "windows" {
-		home = os.Getenv("HOMEDRIVE") + os.Getenv("HOMEPATH")
-		if home == "" {
-			home = os.Getenv("USERPROFILE")
+	if bytes, err := ioutil.ReadFile(TextPath()); err == nil {
+		return string(bytes)
+	}
+	for n := 1; ; n++ {
+		bytes, err := ioutil.ReadFile(backupPath(n))
+		if err == nil {
+			return string(bytes)
+		} else if errors.Is(err, os.ErrNotExist) {
+			return defaultText
 		}
-	} else {
-		home = os.Getenv("HOME")
 	}
+}
 
-	if bytes, err := ioutil.ReadFile(path.Join(home, ".hitman")); err != nil {
-		return defaultText
-	} else {
-		return string(bytes)
-	}
+// Reports whether the input is due to be kept as another backup
+// The newest backup keeps the time its input was written, so backups are at least backupInterval apart
+func backupDue() bool {
+	info, err := os.Stat(backupPath(1))
+	return err != nil || time.Since(info.ModTime()) >= backupInterval
 }
 
-// Save the provided string into $HOME/.hitman
-// Fails silently
-func SaveText(text string) {
-	var home string
+// Save the provided string as the input
+// The previous input is kept as the first of up to backups rotated backups when the newest one
+// is older than backupInterval, so that frequent saves do not push out earlier versions
+// Does nothing when the input has not changed
+func SaveText(text string, backups int) error {
+	file := TextPath()
+	if saved, err := ioutil.ReadFile(file); err == nil && string(saved) == text {
+		return nil
+	}
 
-	if runtime.GOOS == "windows" {
-		home = os.Getenv("HOMEDRIVE") + os.Getenv("HOMEPATH")
-		if home == "" {
-			home = os.Getenv("USERPROFILE")
+	if backups > 0 && backupDue() {
+		for n := backups - 1; n >= 1; n-- {
+			if err := os.Rename(backupPath(n), backupPath(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(file, backupPath(1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
 		}
-	} else {
-		home = os.Getenv("HOME")
 	}
-
-	_ = ioutil.WriteFile(path.Join(home, ".hitman"), []byte(text), 0644)
+	return writeFile(file, []byte(text), 0600)
 }
 
 func CopyText(text string) error {
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setHome(t *testing.T, home string) {
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+}
+
+func readFile(t *testing.T, file string) string {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	return string(bytes)
+}
+
+// Make the newest backup look older than backupInterval
+func ageBackup(t *testing.T) {
+	old := time.Now().Add(-2 * backupInterval)
+	if err := os.Chtimes(backupPath(1), old, old); err != nil {
+		panic(err)
+	}
+}
+
+func TestSaveText(t *testing.T) {
+	setHome(t, t.TempDir())
+
+	if text := LoadText(); text != "GET www.example.com" {
+		t.Log(text)
+		t.Fail()
+	}
+
+	save := func(text string) {
+		if err := SaveText(text, 2); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	save("one")
+	if LoadText() != "one" {
+		t.Fail()
+	}
+	if _, err := os.Stat(backupPath(1)); !os.IsNotExist(err) {
+		t.Fail()
+	}
+
+	// the first save keeps the previous input
+	save("two")
+	if readFile(t, backupPath(1)) != "one" {
+		t.Fail()
+	}
+
+	// saves soon after it do not push it out
+	save("three")
+	save("four")
+	if readFile(t, backupPath(1)) != "one" || readFile(t, TextPath()) != "four" {
+		t.Fail()
+	}
+
+	ageBackup(t)
+	save("five")
+	if readFile(t, backupPath(1)) != "four" || readFile(t, backupPath(2)) != "one" {
+		t.Fail()
+	}
+
+	// only the configured number of backups is kept
+	ageBackup(t)
+	save("six")
+	if readFile(t, backupPath(2)) != "four" {
+		t.Fail()
+	}
+	if _, err := os.Stat(backupPath(3)); !os.IsNotExist(err) {
+		t.Fail()
+	}
+
+	// the newest backup is loaded when the input is gone
+	if err := os.Remove(TextPath()); err != nil {
+		panic(err)
+	}
+	if text := LoadText(); text != "five" {
+		t.Log(text)
+		t.Fail()
+	}
+
+	// no backups are kept when they are turned off
+	ageBackup(t)
+	if err := SaveText("seven", 0); err != nil {
+		t.FailNow()
+	}
+	if readFile(t, backupPath(1)) != "five" {
+		t.Fail()
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "nested", "file.json")
+
+	for _, text := range []string{"first", "second"} {
+		if err := writeFile(file, []byte(text), 0600); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if readFile(t, file) != text {
+			t.Fail()
+		}
+	}
+
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+		t.Fail()
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil || len(entries) != 1 {
+		t.Log(entries)
+		t.Fail()
+	}
+}
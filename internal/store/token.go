@@ -0,0 +1,78 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// An OAuth2 access token cached until it expires
+type Token struct {
+	AccessToken  string    `json:"accessToken"`
+	TokenType    string    `json:"tokenType"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Reports whether the token can still be used for a while
+// Tokens without an expiry never expire
+func (t Token) Valid() bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(30*time.Second).Before(t.Expiry))
+}
+
+// serializes reads and writes of the token cache
+var tokenMu sync.Mutex
+
+func tokenFile() string {
+	return StatePath("tokens.json")
+}
+
+func loadTokens() (map[string]Token, error) {
+	tokens := map[string]Token{}
+
+	bytes, err := ioutil.ReadFile(tokenFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bytes, &tokens); err != nil {
+		return nil, errors.New("corrupt token cache: " + err.Error())
+	}
+	return tokens, nil
+}
+
+// Returns the cached token for the key
+// ok is false if no token is cached
+func LoadToken(key string) (token Token, ok bool, err error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return Token{}, false, err
+	}
+	token, ok = tokens[key]
+	return token, ok, nil
+}
+
+// Cache the token under the key
+func SaveToken(key string, token Token) error {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return err
+	}
+	tokens[key] = token
+
+	bytes, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(tokenFile(), bytes, 0600)
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Extension of request files
+const RequestFileExt = ".http"
+
+// Reports whether a collection or file name can be used without escaping its directory
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && name[0] != '.'
+}
+
+// Returns the directory of a named collection of request files
+func CollectionDir(name string) (string, error) {
+	if !validName(name) {
+		return "", errors.New("invalid collection name: " + name)
+	}
+	return filepath.Join(DataPath("collections"), name), nil
+}
+
+// Returns the directory of a named collection, creating it if it does not exist
+func CreateCollection(name string) (string, error) {
+	dir, err := CollectionDir(name)
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// Returns the names of the request files in a directory, sorted
+func ListRequestFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), RequestFileExt) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Returns the contents of a request file
+// Returns no text when the file does not exist yet
+func LoadFile(file string) (string, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(bytes), err
+}
+
+// Save a request file
+func SaveFile(file string, text string) error {
+	if saved, err := ioutil.ReadFile(file); err == nil && string(saved) == text {
+		return nil
+	}
+	return writeFile(file, []byte(text), 0644)
+}
+
+// Returns the name with the request file extension added if it does not have one
+func requestFileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !validName(name) {
+		return "", errors.New("invalid file name: " + name)
+	}
+	if !strings.HasSuffix(name, RequestFileExt) {
+		name += RequestFileExt
+	}
+	return name, nil
+}
+
+// Create an empty request file in a directory and return its path
+func CreateRequestFile(dir string, name string) (string, error) {
+	name, err := requestFileName(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, name)
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return "", errors.New(name + " already exists")
+	} else if err != nil {
+		return "", err
+	}
+	return file, f.Close()
+}
+
+// Rename a request file within its directory and return its new path
+func RenameRequestFile(file string, name string) (string, error) {
+	name, err := requestFileName(name)
+	if err != nil {
+		return "", err
+	}
+
+	renamed := filepath.Join(filepath.Dir(file), name)
+	if renamed == file {
+		return file, nil
+	}
+	if _, err := os.Lstat(renamed); err == nil {
+		return "", errors.New(name + " already exists")
+	}
+	return renamed, os.Rename(file, renamed)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "requests")
+
+	if names, err := ListRequestFiles(dir); err != nil || len(names) != 0 {
+		t.Fail()
+	}
+
+	file, err := CreateRequestFile(dir, " users ")
+	if err != nil || file != filepath.Join(dir, "users.http") {
+		t.Log(file, err)
+		t.FailNow()
+	}
+	if _, err := CreateRequestFile(dir, "orders.http"); err != nil {
+		t.FailNow()
+	}
+	if _, err := CreateRequestFile(dir, "users"); err == nil {
+		t.Fail()
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		panic(err)
+	}
+
+	if names, err := ListRequestFiles(dir); err != nil || strings.Join(names, " ") != "orders.http users.http" {
+		t.Log(names, err)
+		t.Fail()
+	}
+
+	for _, name := range []string{"", " ", "../escape", `..\escape`, "nested/file", ".hidden"} {
+		if _, err := CreateRequestFile(dir, name); err == nil {
+			t.Log(name)
+			t.Fail()
+		}
+		if _, err := RenameRequestFile(file, name); err == nil {
+			t.Log(name)
+			t.Fail()
+		}
+	}
+
+	if err := SaveFile(file, "GET example.com"); err != nil {
+		t.FailNow()
+	}
+	renamed, err := RenameRequestFile(file, "people")
+	if err != nil || renamed != filepath.Join(dir, "people.http") {
+		t.Log(renamed, err)
+		t.FailNow()
+	}
+	if text, err := LoadFile(renamed); err != nil || text != "GET example.com" {
+		t.Fail()
+	}
+	if text, err := LoadFile(file); err != nil || text != "" {
+		t.Fail()
+	}
+
+	// renaming never replaces another file
+	if _, err := RenameRequestFile(renamed, "orders"); err == nil {
+		t.Fail()
+	}
+	if same, err := RenameRequestFile(renamed, "people.http"); err != nil || same != renamed {
+		t.Fail()
+	}
+}
+
+func TestCollectionDir(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	dir, err := CreateCollection("shop")
+	if err != nil || dir != filepath.Join(home, ".local", "share", "hitman", "collections", "shop") {
+		t.Log(dir, err)
+		t.Fail()
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fail()
+	}
+
+	for _, name := range []string{"", "..", "../shop", "a/b"} {
+		if _, err := CollectionDir(name); err == nil {
+			t.Log(name)
+			t.Fail()
+		}
+	}
+}
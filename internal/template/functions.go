@@ -0,0 +1,160 @@
+package template
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A built-in function called as {{$name arg1 arg2}}
+type function func(args []string) (string, error)
+
+var functions = map[string]function{
+	"$uuid":         uuid,
+	"$timestamp":    timestamp,
+	"$isoTimestamp": isoTimestamp,
+	"$randomInt":    randomInt,
+	"$base64":       base64Encode,
+	"$env":          env,
+}
+
+// Returns a resolver that calls built-in functions for expressions starting with $
+// and passes everything else to next
+func Builtins(next Resolver) Resolver {
+	return func(expr string) (string, error) {
+		if !strings.HasPrefix(expr, "$") {
+			return next(expr)
+		}
+
+		args, err := splitArgs(expr)
+		if err != nil {
+			return "", err
+		}
+		fn, prs := functions[args[0]]
+		if !prs {
+			return "", errors.New("unknown function " + args[0])
+		}
+		value, err := fn(args[1:])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", args[0], err)
+		}
+		return value, nil
+	}
+}
+
+// Split an expression on spaces; quoted arguments may contain spaces
+func splitArgs(expr string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quoted, started bool
+
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case r == ' ' && !quoted:
+			if started {
+				args = append(args, arg.String())
+				arg.Reset()
+				started = false
+			}
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unclosed quote in " + expr)
+	}
+	if started {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+func expectArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %d arguments, got %d", n, len(args))
+	}
+	return nil
+}
+
+// A random version 4 UUID
+func uuid(args []string) (string, error) {
+	if err := expectArgs(args, 0); err != nil {
+		return "", err
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Seconds since the Unix epoch
+func timestamp(args []string) (string, error) {
+	if err := expectArgs(args, 0); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10), nil
+}
+
+// Current UTC time in RFC 3339 format
+func isoTimestamp(args []string) (string, error) {
+	if err := expectArgs(args, 0); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format(time.RFC3339), nil
+}
+
+// A random integer from min up to but not including max
+func randomInt(args []string) (string, error) {
+	if err := expectArgs(args, 2); err != nil {
+		return "", err
+	}
+	min, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	max, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	if max <= min {
+		return "", errors.New("max must be greater than min")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(max-min))
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(min+n.Int64(), 10), nil
+}
+
+// Standard base64 encoding of the argument
+func base64Encode(args []string) (string, error) {
+	if err := expectArgs(args, 1); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+}
+
+// Value of an environment variable of the hitman process
+func env(args []string) (string, error) {
+	if err := expectArgs(args, 1); err != nil {
+		return "", err
+	}
+	value, prs := os.LookupEnv(args[0])
+	if !prs {
+		return "", errors.New(args[0] + " is not set")
+	}
+	return value, nil
+}
@@ -0,0 +1,81 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Select a value from a JSON document with a simple JSONPath like $.items[0].id or $['a key']
+// Strings are returned without quotes; other values are returned as JSON
+func JSONPath(document []byte, path string) (string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return "", errors.New("JSONPath must start with $: " + path)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", errors.New("response body is not JSON")
+	}
+
+	rest := path[1:]
+	for rest != "" {
+		var key string
+		var index = -1
+
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return "", errors.New("unclosed [' in " + path)
+			}
+			key, rest = rest[2:end], rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return "", errors.New("unclosed [ in " + path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return "", errors.New("invalid index in " + path)
+			}
+			index, rest = i, rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				key, rest = rest[1:], ""
+			} else {
+				key, rest = rest[1:end+1], rest[end+1:]
+			}
+		default:
+			return "", errors.New("invalid JSONPath " + path)
+		}
+
+		if index >= 0 {
+			array, ok := value.([]interface{})
+			if !ok || index >= len(array) {
+				return "", fmt.Errorf("%s matched nothing", path)
+			}
+			value = array[index]
+		} else {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s matched nothing", path)
+			}
+			if value, ok = object[key]; !ok {
+				return "", fmt.Errorf("%s matched nothing", path)
+			}
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"errors"
+	"strings"
+)
+
+// Returns the value of the expression written between {{ and }}
+type Resolver func(expr string) (string, error)
+
+// Replace every {{expression}} in text with the value returned by resolve
+// Spaces around the expression are ignored
+func Expand(text string, resolve Resolver) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	var expanded strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			expanded.WriteString(text)
+			return expanded.String(), nil
+		}
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			return "", errors.New("unclosed {{ in " + text)
+		}
+		end += start
+
+		value, err := resolve(strings.TrimSpace(text[start+2 : end]))
+		if err != nil {
+			return "", err
+		}
+
+		expanded.WriteString(text[:start])
+		expanded.WriteString(value)
+		text = text[end+2:]
+	}
+}
+
+// Returns a resolver that looks expressions up in variables
+func Variables(variables map[string]string) Resolver {
+	return func(expr string) (string, error) {
+		if value, prs := variables[expr]; prs {
+			return value, nil
+		}
+		return "", errors.New("unknown variable " + expr)
+	}
+}
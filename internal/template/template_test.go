@@ -0,0 +1,145 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	variables := map[string]string{
+		"host":  "api.example.com",
+		"token": "abc{{123}}",
+	}
+
+	var tests = []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"No variables", `{"id": 1}`, `{"id": 1}`},
+		{"One variable", `https://{{host}}/orders`, `https://api.example.com/orders`},
+		{"Spaces inside braces", `{{ host }}`, `api.example.com`},
+		{"Values are not expanded again", `Bearer {{token}}`, `Bearer abc{{123}}`},
+		{"Several variables", `{{host}}:{{host}}`, `api.example.com:api.example.com`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v, err := Expand(test.input, Variables(variables)); err != nil {
+				t.Fail()
+			} else if v != test.expected {
+				t.Log(v)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestInvalidExpand(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+	}{
+		{"Unknown variable", `{{port}}`},
+		{"Unclosed braces", `{{host`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Expand(test.input, Variables(map[string]string{"host": "x"})); err == nil {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	t.Setenv("HITMAN_TEST", "hello")
+	resolve := Builtins(Variables(map[string]string{"host": "api.example.com"}))
+
+	var tests = []struct {
+		name    string
+		input   string
+		pattern string
+	}{
+		{"UUID", `{{$uuid}}`, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"Timestamp", `{{$timestamp}}`, `^[0-9]+$`},
+		{"ISO timestamp", `{{$isoTimestamp}}`, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`},
+		{"Random int", `{{$randomInt 1 3}}`, `^[12]$`},
+		{"Base64 with quotes", `Basic {{$base64 "user:pass"}}`, `^Basic dXNlcjpwYXNz$`},
+		{"Base64 without quotes", `{{$base64 user:pass}}`, `^dXNlcjpwYXNz$`},
+		{"Process environment", `{{$env HITMAN_TEST}}`, `^hello$`},
+		{"Variables still work", `{{host}}/{{$randomInt 5 6}}`, `^api.example.com/5$`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v, err := Expand(test.input, resolve); err != nil {
+				t.Log(err)
+				t.Fail()
+			} else if !regexp.MustCompile(test.pattern).MatchString(v) {
+				t.Log(v)
+				t.Fail()
+			}
+		})
+	}
+
+	var invalid = []string{
+		`{{$unknown}}`,
+		`{{$randomInt 10 1}}`,
+		`{{$randomInt 1}}`,
+		`{{$base64 "unclosed}}`,
+		`{{$env HITMAN_TEST_UNSET}}`,
+	}
+
+	for _, input := range invalid {
+		t.Run(input, func(t *testing.T) {
+			if _, err := Expand(input, resolve); err == nil {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	document := []byte(`{"access_token": "abc", "expires_in": 3600, "items": [{"id": 7}, {"id": 8}], "a key": true, "nested": {"ok": null}}`)
+
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"$.access_token", "abc"},
+		{"$.expires_in", "3600"},
+		{"$.items[1].id", "8"},
+		{"$.items[0]", `{"id":7}`},
+		{"$['a key']", "true"},
+		{"$.nested.ok", "null"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if v, err := JSONPath(document, test.path); err != nil {
+				t.Log(err)
+				t.Fail()
+			} else if v != test.expected {
+				t.Log(v)
+				t.Fail()
+			}
+		})
+	}
+
+	for _, path := range []string{"access_token", "$.missing", "$.items[5]", "$.items.id", "$.items[x]", "$.items[-1]"} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := JSONPath(document, path); err == nil {
+				t.Fail()
+			}
+		})
+	}
+
+	// a negative index is not read as the empty key of an object
+	if v, err := JSONPath([]byte(`{"": "empty"}`), "$[-1]"); err == nil || !strings.Contains(err.Error(), "invalid index") {
+		t.Log(v, err)
+		t.Fail()
+	}
+}